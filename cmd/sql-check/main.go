@@ -4,23 +4,51 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sql-check/internal/auditor"
+	"sql-check/internal/baseline"
+	"sql-check/internal/config"
+	"sql-check/internal/diff"
 	"sql-check/internal/extractor"
 	"sql-check/internal/model"
 	"sql-check/internal/parser"
 	"sql-check/internal/reporter"
 	"sql-check/internal/scanner"
+	"sql-check/internal/stats"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// Exit codes
+const (
+	exitClean  = 0 // No issue at or above the --fail-on level
+	exitIssues = 1 // Issues at or above the --fail-on level were found
+	exitError  = 2 // The tool itself failed
+)
 
 var (
-	srcPath    string
-	schemaPath string
-	reportFmt  string
-	outputFile string
-	excludes   []string
+	srcPath     string
+	schemaPaths []string
+	statsPath   string
+	reportFmt   string
+	outputFile  string
+	excludes    []string
+	extensions  []string
+	workers     int
+	configPath  string
+
+	parseErrorLevel string
+	baselinePath    string
+	baselineOut     string
+	failOn          string
+	junitGroupBy    string
+	linkTemplate    string
+	diffBase        string
+	diffFile        string
+
+	// exitCode is set by the analysis when issues should fail the run
+	exitCode = exitClean
 )
 
 var rootCmd = &cobra.Command{
@@ -30,75 +58,410 @@ var rootCmd = &cobra.Command{
 parses them, and checks against a provided database schema for 
 common performance pitfalls like missing indexes, full table scans, etc.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fmt.Printf("Scanning source: %s\n", srcPath)
-		if len(excludes) > 0 {
-			fmt.Printf("Excluding patterns: %v\n", excludes)
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
 		}
-		if schemaPath != "" {
-			fmt.Printf("Using schema: %s\n", schemaPath)
-		}
-		fmt.Printf("Report format: %s\n", reportFmt)
+
+		printSettings(cfg)
+		logf("Report format: %s\n", reportFmt)
 		
-		return runAnalysis()
+		return runAnalysis(cfg)
+	},
+}
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Record the current issues as a baseline",
+	Long: `baseline scans the source like sql-check does and writes every issue
+found to a baseline file. Running sql-check with --baseline then only
+reports issues that are not in the baseline.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		printSettings(cfg)
+
+		result, err := collectIssues(cfg)
+		if err != nil {
+			return err
+		}
+
+		b := baseline.New(result.Issues, srcPath)
+		if err := b.Save(baselineOut); err != nil {
+			return fmt.Errorf("failed to write baseline: %w", err)
+		}
+		logf("Baseline with %d issues written to %s\n", len(b.Issues), baselineOut)
+		return nil
 	},
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&srcPath, "src", "s", ".", "Path to source code to scan")
-	rootCmd.Flags().StringVarP(&schemaPath, "schema", "S", "schema.sql", "Path to database schema SQL file")
-	rootCmd.Flags().StringVarP(&reportFmt, "report", "r", "console", "Report format (console, html)")
-	rootCmd.Flags().StringVarP(&outputFile, "out", "o", "", "Output file path (default: 'report.html' for html)")
-	rootCmd.Flags().StringSliceVarP(&excludes, "exclude", "e", []string{".git", "vendor", "*_test.go"}, "Glob patterns to exclude from scan")
+	// Scan settings are shared by all commands
+	flags := rootCmd.PersistentFlags()
+	flags.StringVarP(&srcPath, "src", "s", ".", "Path to source code to scan")
+	flags.StringSliceVarP(&schemaPaths, "schema", "S", []string{"schema.sql"}, "Path to database schema SQL file or migrations directory, optionally as database=path (repeatable)")
+	flags.StringVar(&statsPath, "stats", "", "Table statistics file (YAML/JSON, or SHOW TABLE STATUS / information_schema output) for size-aware levels")
+	flags.StringSliceVarP(&excludes, "exclude", "e", []string{".git", "vendor", "*_test.go"}, "Glob patterns to exclude from scan")
+	flags.StringSliceVar(&extensions, "ext", []string{"go", "py", "cpp", "sql"}, "File extensions to scan")
+	flags.IntVar(&workers, "workers", 10, "Number of concurrent extraction workers")
+	flags.StringVarP(&configPath, "config", "c", "", "Config file (default: .sql-check.yaml in the source path)")
+	flags.StringVar(&parseErrorLevel, "parse-error-level", "warning", "Level of PARSE_ERROR issues (fatal, warning, suggestion)")
+
+	rootCmd.Flags().StringVarP(&reportFmt, "report", "r", "console", "Report format (console, html, markdown, sarif, json, ndjson, junit, github, gitlab)")
+	rootCmd.Flags().StringVarP(&outputFile, "out", "o", "", "Output file path (default: 'report.html' for html, stdout for others)")
+	rootCmd.Flags().StringVarP(&baselinePath, "baseline", "b", "", "Baseline file, issues recorded in it are not reported")
+	rootCmd.Flags().StringVar(&linkTemplate, "link-template", "", "Link locations in the markdown report, e.g. 'https://github.com/org/repo/blob/main/{path}#L{line}'")
+	rootCmd.Flags().StringVar(&junitGroupBy, "junit-group-by", reporter.JUnitGroupByFile, "Test suites of the junit report: one per file or per rule")
+	rootCmd.Flags().StringVar(&diffBase, "diff", "", "Only report issues on lines changed since this git ref (e.g. origin/main)")
+	rootCmd.Flags().StringVar(&diffFile, "diff-file", "", "Only report issues on lines changed in this unified diff ('-' for stdin)")
+	rootCmd.Flags().StringVar(&failOn, "fail-on", "fatal", "Exit with code 1 if issues at or above this level are found (fatal, warning, suggestion, none)")
+
+	baselineCmd.Flags().StringVarP(&baselineOut, "out", "o", baseline.DefaultFile, "Baseline file to write")
+	rootCmd.AddCommand(baselineCmd)
 }
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run executes the command line args and returns the exit code
+func run(args []string) int {
+	exitCode = exitClean
+	rootCmd.SetArgs(args)
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitCode
+}
+
+// loadConfig reads the project config file and applies its values to every
+// flag that was not set explicitly on the command line.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	var cfg *config.Config
+	var err error
+	if configPath != "" {
+		cfg, err = config.Load(configPath)
+	} else {
+		cfg, err = config.Discover(srcPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var known []string
+	for _, rule := range auditor.DefaultRules() {
+		known = append(known, rule.Name())
+	}
+	if err := cfg.Validate(known); err != nil {
+		return nil, err
+	}
+
+	flags := cmd.Flags()
+	if !flags.Changed("schema") && len(cfg.Schema) > 0 {
+		schemaPaths = cfg.Schema
+	}
+	if !flags.Changed("stats") && cfg.Stats != "" {
+		statsPath = cfg.Stats
 	}
+	if !flags.Changed("ext") && len(cfg.Extensions) > 0 {
+		extensions = cfg.Extensions
+	}
+	if !flags.Changed("exclude") && len(cfg.Excludes) > 0 {
+		excludes = cfg.Excludes
+	}
+	if !flags.Changed("workers") && cfg.Workers > 0 {
+		workers = cfg.Workers
+	}
+	if !flags.Changed("parse-error-level") && cfg.ParseErrorLevel != "" {
+		parseErrorLevel = cfg.ParseErrorLevel
+	}
+	if flags.Lookup("baseline") != nil && !flags.Changed("baseline") && cfg.Baseline != "" {
+		baselinePath = cfg.Baseline
+	}
+	if flags.Lookup("fail-on") != nil && !flags.Changed("fail-on") && cfg.FailOn != "" {
+		failOn = cfg.FailOn
+	}
+
+	return cfg, nil
 }
 
-func runAnalysis() error {
+// registerRules adds the built-in rules to the auditor according to the
+// per-rule settings of the config file.
+func registerRules(auditEngine *auditor.Auditor, cfg *config.Config) error {
+	for _, rule := range auditor.DefaultRules() {
+		rc := cfg.Rules[rule.Name()]
+		if !rc.IsEnabled() {
+			continue
+		}
+
+		if len(rc.Params) > 0 {
+			configurable, ok := rule.(auditor.Configurable)
+			if !ok {
+				return fmt.Errorf("rule %s does not take parameters", rule.Name())
+			}
+			if err := configurable.Configure(rc.Params); err != nil {
+				return fmt.Errorf("rule %s: %w", rule.Name(), err)
+			}
+		}
+
+		if rc.Level != "" {
+			level, err := model.ParseRiskLevel(rc.Level)
+			if err != nil {
+				return fmt.Errorf("rule %s: %w", rule.Name(), err)
+			}
+			auditEngine.SetLevel(rule.Name(), level)
+		}
+
+		auditEngine.Register(rule)
+	}
+	return nil
+}
+
+// logf prints progress messages. They go to stderr so that reports written
+// to stdout stay machine-readable.
+func logf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+func printSettings(cfg *config.Config) {
+	logf("Scanning source: %s\n", srcPath)
+	if cfg.Path != "" {
+		logf("Using config: %s\n", cfg.Path)
+	}
+	if len(excludes) > 0 {
+		logf("Excluding patterns: %v\n", excludes)
+	}
+	if len(schemaPaths) > 0 {
+		logf("Using schema: %v\n", schemaPaths)
+	}
+}
+
+func runAnalysis(cfg *config.Config) error {
+	if junitGroupBy != reporter.JUnitGroupByFile && junitGroupBy != reporter.JUnitGroupByRule {
+		return fmt.Errorf("invalid --junit-group-by %q (expected file or rule)", junitGroupBy)
+	}
+
+	// "none" never fails, otherwise the lowest failing level
+	var failLevel model.RiskLevel
+	if !strings.EqualFold(failOn, "none") {
+		level, err := model.ParseRiskLevel(failOn)
+		if err != nil {
+			return fmt.Errorf("invalid --fail-on: %w", err)
+		}
+		failLevel = level
+	}
+
+	changes, err := loadChanges()
+	if err != nil {
+		return err
+	}
+
+	result, err := collectIssues(cfg)
+	if err != nil {
+		return err
+	}
+	issues := result.Issues
+
+	// Drop issues already known from the baseline
+	if baselinePath != "" {
+		b, err := baseline.Load(baselinePath)
+		if err != nil {
+			return fmt.Errorf("failed to load baseline: %w", err)
+		}
+		var known int
+		issues, known = b.Filter(issues, srcPath)
+		logf("Baseline %s: %d known issues hidden.\n", baselinePath, known)
+	}
+
+	// Drop issues outside the changed lines
+	if changes != nil {
+		var dropped int
+		issues, dropped = changes.Filter(issues)
+		logf("Diff: %d changed files, %d issues outside changed lines hidden.\n", changes.Files(), dropped)
+	}
+
+	// 5. Report
+	var rpt model.Reporter
+	
+	switch reportFmt {
+	case "console":
+		rpt = reporter.NewConsoleReporter()
+	case "html":
+		target := outputFile
+		if target == "" {
+			target = "report.html"
+		}
+		rpt = reporter.NewHTMLReporter(target)
+	case "sarif":
+		rpt = reporter.NewSARIFReporter(outputFile, srcPath, result.Rules)
+	case "json":
+		rpt = reporter.NewJSONReporter(outputFile, srcPath)
+	case "ndjson":
+		rpt = reporter.NewNDJSONReporter(outputFile, srcPath)
+	case "markdown":
+		rpt = reporter.NewMarkdownReporter(outputFile, srcPath, linkTemplate)
+	case "github":
+		rpt = reporter.NewGitHubActionsReporter(outputFile)
+	case "gitlab":
+		rpt = reporter.NewGitLabReporter(outputFile, srcPath)
+	case "junit":
+		rpt = reporter.NewJUnitReporter(outputFile, junitGroupBy, result.Segments, result.Rules)
+	default:
+		return fmt.Errorf("unknown report format %q (expected console, html, markdown, sarif, json, ndjson, junit, github or gitlab)", reportFmt)
+	}
+
+	if err := rpt.Report(issues); err != nil {
+		return fmt.Errorf("reporting failed: %w", err)
+	}
+
+	// 6. Gate on the most severe issues, whatever the report format
+	if failLevel != "" {
+		failing := 0
+		for _, issue := range issues {
+			if !issue.Suppressed && issue.Level.AtLeast(failLevel) {
+				failing++
+			}
+		}
+		if failing > 0 {
+			logf("Failing: %d issues at or above %s.\n", failing, failLevel)
+			exitCode = exitIssues
+		}
+	}
+
+	return nil
+}
+
+// loadChanges reads the changed lines given by --diff or --diff-file. It
+// returns nil if neither is set.
+func loadChanges() (*diff.Changes, error) {
+	switch {
+	case diffBase != "" && diffFile != "":
+		return nil, fmt.Errorf("--diff and --diff-file cannot be used together")
+	case diffBase != "":
+		dir := srcPath
+		if info, err := os.Stat(srcPath); err == nil && !info.IsDir() {
+			dir = filepath.Dir(srcPath)
+		}
+		changes, err := diff.FromGit(diffBase, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff against %s: %w", diffBase, err)
+		}
+		return changes, nil
+	case diffFile != "":
+		in := os.Stdin
+		if diffFile != "-" {
+			f, err := os.Open(diffFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read diff: %w", err)
+			}
+			defer f.Close()
+			in = f
+		}
+		// Paths in the diff are relative to the working directory
+		changes, err := diff.Parse(in, ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read diff: %w", err)
+		}
+		return changes, nil
+	}
+	return nil, nil
+}
+
+// analysis is the outcome of a scan
+type analysis struct {
+	Issues   []model.Issue
+	Segments []model.SQLSegment // Segments the rules ran on, with or without issues
+	Rules    []model.Rule       // Rules that ran
+}
+
+// collectIssues scans the source tree and audits every SQL segment found
+func collectIssues(cfg *config.Config) (*analysis, error) {
 	// 0. Validate Inputs
 	if _, err := os.Stat(srcPath); os.IsNotExist(err) {
-		return fmt.Errorf("source path does not exist: %s", srcPath)
+		return nil, fmt.Errorf("source path does not exist: %s", srcPath)
+	}
+	parseLevel, err := model.ParseRiskLevel(parseErrorLevel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --parse-error-level: %w", err)
 	}
 
 	// 1. Initialize Extractor Manager
 	mgr := extractor.NewManager()
-	// Register generic regex extractor for all supported types for now
+	// Go sources get the AST-based extractor, SQL scripts are split into
+	// statements, others use the generic regex one
 	generic := extractor.NewRegexExtractor()
-	mgr.Register("go", generic)
-	mgr.Register("py", generic)
-	mgr.Register("cpp", generic)
+	for _, ext := range extensions {
+		mgr.Register(ext, generic)
+	}
+	mgr.Register("go", extractor.NewGoExtractor())
+	mgr.Register("sql", extractor.NewSQLFileExtractor())
 	
 	// Initialize Parser & Schema
 	sqlParser := parser.NewSQLParser()
+	if cfg.LowerCaseTableNames != nil {
+		sqlParser.LowerCaseTableNames = *cfg.LowerCaseTableNames
+	}
 	var schema *model.SchemaCtx
-	if schemaPath != "" {
+	var existing []string
+	for _, arg := range schemaPaths {
 		// Check if schema file exists
-		if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
-			fmt.Printf("Warning: Schema file not found at %s. Proceeding without context-aware checks.\n", schemaPath)
-		} else {
-			var err error
-			fmt.Printf("Loading schema from %s...\n", schemaPath)
-			schema, err = sqlParser.LoadSchema(schemaPath)
-			if err != nil {
-				return fmt.Errorf("failed to load schema: %w", err)
-			}
-			fmt.Printf("Schema loaded. Found %d tables.\n", len(schema.Tables))
+		_, path := config.SplitSchemaPath(arg)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			logf("Warning: Schema file not found at %s. Proceeding without context-aware checks.\n", path)
+			continue
+		}
+		existing = append(existing, arg)
+	}
+	if len(existing) > 0 {
+		logf("Loading schema from %v...\n", existing)
+		schema, err = sqlParser.LoadSchemas(existing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load schema: %w", err)
+		}
+		logf("Schema loaded. Found %d tables.\n", len(schema.Tables))
+	}
+	// Ensure schema is not nil if not loaded (empty context)
+	if schema == nil {
+		schema = model.NewSchemaCtx(sqlParser.LowerCaseTableNames)
+	}
+	schema.DefaultDatabase = cfg.DefaultDatabase
+	if statsPath != "" {
+		tableStats, err := stats.Load(statsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load statistics: %w", err)
 		}
+		if missing := tableStats.Apply(schema); len(missing) > 0 {
+			logf("Warning: statistics for tables not in the schema: %v\n", missing)
+		}
+		logf("Statistics loaded for %d tables.\n", len(tableStats.Tables))
+	}
+
+	// Initialize Auditor with the configured rules
+	auditEngine := auditor.NewAuditor(schema, sqlParser)
+	auditEngine.ParseErrorLevel = parseLevel
+	if cfg.TableSize.Small != nil {
+		auditEngine.SmallTableRows = *cfg.TableSize.Small
+	}
+	if cfg.TableSize.Large != nil {
+		auditEngine.LargeTableRows = *cfg.TableSize.Large
+	}
+	if err := registerRules(auditEngine, cfg); err != nil {
+		return nil, fmt.Errorf("invalid rule config: %w", err)
 	}
 
 	// 2. Initialize Scanner
-	walker := scanner.NewFileWalker([]string{"go", "py", "cpp", "sql"}, excludes)
+	walker := scanner.NewFileWalker(extensions, excludes)
 
 	
 	ctx := context.Background()
 	paths, errChan := walker.Walk(ctx, srcPath)
 
 	// 3. Start Worker Pool
-	pool := scanner.NewWorkerPool(10, func(path string) ([]model.SQLSegment, error) {
+	pool := scanner.NewWorkerPool(workers, func(path string) ([]model.SQLSegment, error) {
 		return mgr.Extract(path)
 	})
 	results := pool.Start(ctx, paths)
@@ -107,11 +470,11 @@ func runAnalysis() error {
 	var allSegments []model.SQLSegment
 	go func() {
 		for err := range errChan {
-			fmt.Printf("Scanner Error: %v\n", err)
+			logf("Scanner Error: %v\n", err)
 		}
 	}()
 
-	fmt.Printf("Scanning started on %s...\n", srcPath)
+	logf("Scanning started on %s...\n", srcPath)
 	for res := range results {
 		if res.Error != nil {
 			// fmt.Printf("Extract Error on %s: %v\n", res.File, res.Error) // Optional verbose logging
@@ -121,46 +484,15 @@ func runAnalysis() error {
 			allSegments = append(allSegments, res.Segments...)
 		}
 	}
-	fmt.Printf("Scan complete. Validating %d SQL segments...\n", len(allSegments))
+	logf("Scan complete. Validating %d SQL segments...\n", len(allSegments))
 
 	// 4. Audit
-	// Ensure schema is not nil if not loaded (empty context)
-	if schema == nil {
-		schema = &model.SchemaCtx{Tables: map[string]*model.Table{}}
-	}
-
-	auditEngine := auditor.NewAuditor(schema, sqlParser)
-	auditEngine.Register(&auditor.NoWhereRule{})
-	auditEngine.Register(&auditor.SelectStarRule{})
-	auditEngine.Register(&auditor.IndexMissRule{})
-	auditEngine.Register(&auditor.ImplicitConversionRule{})
-	auditEngine.Register(&auditor.DeepPaginationRule{Threshold: 5000})
-	auditEngine.Register(&auditor.NegativeQueryRule{})
-
 	issues, err := auditEngine.Audit(allSegments)
 	if err != nil {
-		return fmt.Errorf("audit failed: %w", err)
+		return nil, fmt.Errorf("audit failed: %w", err)
 	}
 
-	// 5. Report
-	var rpt model.Reporter
-	
-	switch reportFmt {
-	case "html":
-		target := outputFile
-		if target == "" {
-			target = "report.html"
-		}
-		rpt = reporter.NewHTMLReporter(target)
-	default:
-		rpt = reporter.NewConsoleReporter()
-	}
-
-	if err := rpt.Report(issues); err != nil {
-		return fmt.Errorf("reporting failed: %w", err)
-	}
-
-	return nil
+	return &analysis{Issues: issues, Segments: auditEngine.Audited(), Rules: auditEngine.Rules()}, nil
 }
 
 
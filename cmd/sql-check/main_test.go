@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSchema = `CREATE TABLE users (
+    id BIGINT AUTO_INCREMENT,
+    email VARCHAR(255),
+    PRIMARY KEY (id),
+    KEY idx_email (email)
+);
+CREATE TABLE orders (
+    id BIGINT AUTO_INCREMENT,
+    user_id BIGINT,
+    amount DECIMAL(10, 2),
+    PRIMARY KEY (id),
+    KEY idx_user (user_id)
+);
+`
+
+// Sources whose most severe issue is of the given level
+var levelSources = map[string]string{
+	"clean":      "SELECT id FROM users WHERE id = ?;\n",
+	"suggestion": "SELECT * FROM users WHERE id = ?;\n",
+	"warning":    "SELECT id FROM orders WHERE amount = ?;\n",
+	"fatal":      "DELETE FROM users;\n",
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.sql")
+	if err := os.WriteFile(schema, []byte(testSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "report.json")
+
+	// Exit code by --fail-on threshold, then by the most severe issue found
+	want := map[string]map[string]int{
+		"fatal":      {"clean": exitClean, "suggestion": exitClean, "warning": exitClean, "fatal": exitIssues},
+		"warning":    {"clean": exitClean, "suggestion": exitClean, "warning": exitIssues, "fatal": exitIssues},
+		"suggestion": {"clean": exitClean, "suggestion": exitIssues, "warning": exitIssues, "fatal": exitIssues},
+		"none":       {"clean": exitClean, "suggestion": exitClean, "warning": exitClean, "fatal": exitClean},
+	}
+
+	for level, sql := range levelSources {
+		src := filepath.Join(dir, level)
+		if err := os.MkdirAll(src, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, "queries.sql"), []byte(sql), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for failOn, byLevel := range want {
+		for level, code := range byLevel {
+			args := []string{"--src", filepath.Join(dir, level), "--schema", schema, "--report", "json", "--out", out, "--fail-on", failOn}
+			if got := run(args); got != code {
+				t.Errorf("--fail-on %s with %s source: got exit code %d, want %d", failOn, level, got, code)
+			}
+		}
+	}
+}
+
+func TestRun_ExitCodeOnError(t *testing.T) {
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "report.json")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"Invalid threshold", []string{"--src", dir, "--report", "json", "--out", out, "--fail-on", "severe"}},
+		{"Missing source", []string{"--src", filepath.Join(dir, "missing"), "--report", "json", "--out", out, "--fail-on", "fatal"}},
+		{"Unknown report format", []string{"--src", dir, "--report", "pdf", "--fail-on", "fatal"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(tt.args); got != exitError {
+				t.Errorf("got exit code %d, want %d", got, exitError)
+			}
+		})
+	}
+}
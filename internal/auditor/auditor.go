@@ -2,21 +2,47 @@ package auditor
 
 import (
 	"fmt"
+	"os"
 	"sql-check/internal/model"
 	"sql-check/internal/parser"
+
+	"github.com/pingcap/tidb/parser/ast"
 )
 
+// ParseErrorRule is the rule name recorded on PARSE_ERROR issues
+const ParseErrorRule = "parse_error"
+
 type Auditor struct {
 	rules  []model.Rule
+	levels map[string]model.RiskLevel // Level overrides by rule name
 	schema *model.SchemaCtx
 	parser *parser.SQLParser
+
+	// ParseErrorLevel is the level of PARSE_ERROR issues
+	ParseErrorLevel model.RiskLevel
+	// MinConfidence is the segment confidence required to report a parse error.
+	// Strings below it are assumed not to be SQL at all and are skipped silently.
+	MinConfidence float64
+
+	// Issues of SizeAware rules are downgraded one level when the rows they
+	// are about number fewer than SmallTableRows, and escalated when they
+	// number LargeTableRows or more. Zero disables either.
+	SmallTableRows int64
+	LargeTableRows int64
+
+	audited []model.SQLSegment // Segments of the last Audit that were parsed
 }
 
 func NewAuditor(schema *model.SchemaCtx, p *parser.SQLParser) *Auditor {
 	return &Auditor{
-		rules:  make([]model.Rule, 0),
-		schema: schema,
-		parser: p,
+		rules:           make([]model.Rule, 0),
+		levels:          make(map[string]model.RiskLevel),
+		schema:          schema,
+		parser:          p,
+		ParseErrorLevel: model.RiskLevelWarning,
+		MinConfidence:   0.5,
+		SmallTableRows:  10000,
+		LargeTableRows:  10000000,
 	}
 }
 
@@ -24,31 +50,177 @@ func (a *Auditor) Register(rule model.Rule) {
 	a.rules = append(a.rules, rule)
 }
 
+// SetLevel overrides the level of every issue reported by the named rule
+func (a *Auditor) SetLevel(ruleName string, level model.RiskLevel) {
+	a.levels[ruleName] = level
+}
+
+// Rules returns the registered rules
+func (a *Auditor) Rules() []model.Rule {
+	return a.rules
+}
+
+// Audited returns the segments of the last Audit call that were parsed and
+// checked by the rules. Segments that failed to parse are left out.
+func (a *Auditor) Audited() []model.SQLSegment {
+	return a.audited
+}
+
 func (a *Auditor) Audit(segments []model.SQLSegment) ([]model.Issue, error) {
 	var allIssues []model.Issue
+	a.audited = nil
 
 	for _, seg := range segments {
-		// 1. Parse SQL
-		stmt, err := a.parser.Parse(seg.SQL)
+		// 1. Parse SQL, a segment may hold several statements
+		stmts, err := a.parser.ParseAll(seg.SQL)
 		if err != nil {
-			// Report parse error as a warning or info?
-			// For now, let's skip or log.
-			// Ideally add a "ParseError" issue.
+			if issue, ok := a.parseErrorIssue(&seg, err); ok {
+				allIssues = append(allIssues, applySuppressions(&seg, []model.Issue{issue})...)
+			}
 			continue
 		}
+		a.audited = append(a.audited, seg)
 
-		// 2. Run Rules
-		for _, rule := range a.rules {
-			issues, err := rule.Check(&seg, stmt, a.schema)
-			if err != nil {
-				fmt.Printf("Error running rule %s: %v\n", rule.Name(), err)
-				continue
-			}
-			if len(issues) > 0 {
-				allIssues = append(allIssues, issues...)
+		// 2. Run Rules on every statement
+		for _, stmt := range stmts {
+			var binding *parser.Binding
+			for _, rule := range a.rules {
+				var issues []model.Issue
+				var err error
+				if bound, ok := rule.(BoundRule); ok {
+					if binding == nil {
+						binding = parser.BindStatement(stmt, a.schema)
+					}
+					issues, err = bound.CheckBound(&seg, stmt.Node, binding, a.schema)
+				} else {
+					issues, err = rule.Check(&seg, stmt.Node, a.schema)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error running rule %s: %v\n", rule.Name(), err)
+					continue
+				}
+				level, override := a.levels[rule.Name()]
+				for i := range issues {
+					issues[i].Rule = rule.Name()
+					if override {
+						issues[i].Level = level
+					} else {
+						a.adjustForSize(rule, stmt.Node, &issues[i])
+					}
+					issues[i].StatementIndex = stmt.Index
+					issues[i].StatementOffset = stmt.Offset
+				}
+				allIssues = append(allIssues, applySuppressions(&seg, issues)...)
 			}
 		}
 	}
 
 	return allIssues, nil
 }
+
+// adjustForSize changes the level of an issue of a SizeAware rule by the
+// estimated number of rows it is about: those of its table, or of one key
+// of its index, or else those of the largest table the statement touches
+func (a *Auditor) adjustForSize(rule model.Rule, node ast.StmtNode, issue *model.Issue) {
+	if _, ok := rule.(SizeAware); !ok {
+		return
+	}
+
+	rows, note, known := a.issueRows(node, issue)
+	if rows == 0 {
+		return
+	}
+
+	switch {
+	case a.LargeTableRows > 0 && rows >= a.LargeTableRows:
+		issue.Level = issue.Level.Escalated()
+	case a.SmallTableRows > 0 && rows < a.SmallTableRows && known:
+		issue.Level = issue.Level.Downgraded()
+	default:
+		return
+	}
+	issue.Message += " (" + note + ")"
+}
+
+// issueRows estimates the rows an issue is about, 0 if unknown. known is
+// false if tables of the statement without an estimate may hold more.
+func (a *Auditor) issueRows(node ast.StmtNode, issue *model.Issue) (rows int64, note string, known bool) {
+	if table := issue.Table; table != nil {
+		if table.Rows == 0 {
+			return 0, "", false
+		}
+		if idx := issue.Index; idx != nil && idx.Cardinality > 0 {
+			rows = max(table.Rows/idx.Cardinality, 1)
+			return rows, fmt.Sprintf("~%d rows of table %s per key of %s", rows, table.Name, idx.Name), true
+		}
+		return table.Rows, fmt.Sprintf("table %s has ~%d rows", table.Name, table.Rows), true
+	}
+
+	var largest *model.Table
+	known = true
+	for _, tn := range parser.ExtractTables(node) {
+		table, ok := a.schema.Lookup(tn.Schema.O, tn.Name.O)
+		if !ok || table.Rows == 0 {
+			known = false // A table without an estimate may be large
+			continue
+		}
+		if largest == nil || table.Rows > largest.Rows {
+			largest = table
+		}
+	}
+	if largest == nil {
+		return 0, "", false
+	}
+	return largest.Rows, fmt.Sprintf("table %s has ~%d rows", largest.Name, largest.Rows), known
+}
+
+// applySuppressions marks the issues silenced by the segment's inline
+// "sql-check:ignore" comments.
+func applySuppressions(seg *model.SQLSegment, issues []model.Issue) []model.Issue {
+	for i := range issues {
+		for _, s := range seg.Suppressions {
+			if s.Matches(issues[i]) {
+				issues[i].Suppressed = true
+				issues[i].SuppressReason = s.Reason
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// parseErrorIssue turns a parse failure into a PARSE_ERROR issue, unless the
+// segment is unlikely to be SQL in the first place.
+func (a *Auditor) parseErrorIssue(seg *model.SQLSegment, err error) (model.Issue, bool) {
+	confidence := seg.Confidence
+	if confidence == 0 {
+		confidence = parser.Confidence(seg.SQL)
+	}
+	if confidence < a.MinConfidence {
+		return model.Issue{}, false
+	}
+
+	msg := fmt.Sprintf("SQL could not be parsed: %v", err)
+	offset := 0
+	if pe, ok := parser.AsParseError(err); ok {
+		msg = fmt.Sprintf("SQL could not be parsed at line %d column %d near %q", pe.Line, pe.Column, truncate(pe.Near, 40))
+		offset = pe.Offset
+	}
+
+	return model.Issue{
+		Type:       "PARSE_ERROR",
+		Level:      a.ParseErrorLevel,
+		Message:    msg,
+		Suggestion: "Fix the syntax error. If the statement uses a dialect feature the parser does not support, it cannot be audited.",
+		Segment:    *seg,
+		Rule:       ParseErrorRule,
+		Offset:     offset,
+	}, true
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
@@ -1,8 +1,10 @@
 package auditor
 
 import (
+	"fmt"
 	"sql-check/internal/model"
 	"sql-check/internal/parser"
+	"strings"
 	"testing"
 
 	"github.com/pingcap/tidb/parser/ast"
@@ -77,3 +79,363 @@ func TestAuditor_Audit_ParseError(t *testing.T) {
 		t.Errorf("Expected 0 issues for invalid SQL, got %d", len(issues))
 	}
 }
+
+func TestAuditor_Audit_ParseErrorIssue(t *testing.T) {
+	p := parser.NewSQLParser()
+	a := NewAuditor(nil, p)
+	a.ParseErrorLevel = model.RiskLevelFatal
+
+	segments := []model.SQLSegment{
+		{SQL: "SELECT * FROM users WHERE"},
+		{SQL: "SELECTING items is fun"},
+		{SQL: "Select the row you like."},
+	}
+
+	issues, err := a.Audit(segments)
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Type != "PARSE_ERROR" || issues[0].Level != model.RiskLevelFatal {
+		t.Errorf("Unexpected issue %s/%s", issues[0].Type, issues[0].Level)
+	}
+}
+
+func TestAuditor_Audited(t *testing.T) {
+	p := parser.NewSQLParser()
+	a := NewAuditor(nil, p)
+
+	segments := []model.SQLSegment{
+		{SQL: "SELECT id FROM users"},
+		{SQL: "SELECTING items is fun"},
+		{SQL: "SELECT * FROM users WHERE"},
+		{SQL: "DELETE FROM users WHERE id = 1"},
+	}
+	if _, err := a.Audit(segments); err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+
+	var audited []string
+	for _, seg := range a.Audited() {
+		audited = append(audited, seg.SQL)
+	}
+	if strings.Join(audited, "|") != "SELECT id FROM users|DELETE FROM users WHERE id = 1" {
+		t.Errorf("Unexpected audited segments %q", audited)
+	}
+
+	if _, err := a.Audit(segments[:1]); err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+	if len(a.Audited()) != 1 {
+		t.Errorf("Audited() should only hold the last call, got %d segments", len(a.Audited()))
+	}
+}
+
+func TestAuditor_Audit_MultiStatement(t *testing.T) {
+	p := parser.NewSQLParser()
+	a := NewAuditor(nil, p)
+	a.Register(&NoWhereRule{})
+
+	sql := "SELECT 1; DELETE FROM tmp; UPDATE users SET x = 1"
+	issues, err := a.Audit([]model.SQLSegment{{SQL: sql}})
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d", len(issues))
+	}
+
+	want := []struct {
+		typ    string
+		index  int
+		offset int
+	}{
+		{"UNSAFE_DELETE", 1, 10},
+		{"UNSAFE_UPDATE", 2, 27},
+	}
+	for i, w := range want {
+		got := issues[i]
+		if got.Type != w.typ || got.StatementIndex != w.index || got.StatementOffset != w.offset {
+			t.Errorf("issue %d: got %s index=%d offset=%d, want %s index=%d offset=%d",
+				i, got.Type, got.StatementIndex, got.StatementOffset, w.typ, w.index, w.offset)
+		}
+	}
+}
+
+func TestAuditor_Audit_Placeholders(t *testing.T) {
+	schema := model.NewSchemaCtx(1)
+	schema.AddTable(&model.Table{
+		Name:    "events",
+		Columns: map[string]*model.Column{"id": {Name: "id", Type: "bigint"}, "created_at": {Name: "created_at", Type: "datetime"}},
+		Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}}, {Name: "idx_created", Columns: []string{"created_at"}}},
+	})
+	a := NewAuditor(schema, parser.NewSQLParser())
+	a.Register(&NonSargableRule{})
+
+	tests := map[string]string{
+		"SELECT 1; SELECT id FROM events WHERE id = $1 AND DATE(created_at) = $2": "created_at >= $2 AND created_at < $2 + INTERVAL 1 DAY",
+		"SELECT id FROM events WHERE UNIX_TIMESTAMP(created_at) > :since":         "created_at > FROM_UNIXTIME(:since)",
+		"SELECT id FROM events WHERE id + @p1 = ? AND DATE(created_at) = '?'":     "id = ? - @p1",
+	}
+	for sql, want := range tests {
+		issues, err := a.Audit([]model.SQLSegment{{SQL: sql}})
+		if err != nil {
+			t.Fatalf("Audit() error = %v", err)
+		}
+		if len(issues) == 0 || !strings.Contains(issues[0].Suggestion, want) {
+			t.Errorf("Audit(%q) = %v, want a suggestion containing %q", sql, issues, want)
+		}
+	}
+}
+
+func TestAuditor_SetLevel(t *testing.T) {
+	p := parser.NewSQLParser()
+	a := NewAuditor(nil, p)
+	a.Register(&SelectStarRule{})
+	a.SetLevel("select_star", model.RiskLevelFatal)
+
+	issues, err := a.Audit([]model.SQLSegment{{SQL: "SELECT * FROM users"}})
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Level != model.RiskLevelFatal || issues[0].Rule != "select_star" {
+		t.Errorf("Unexpected issue level=%s rule=%s", issues[0].Level, issues[0].Rule)
+	}
+}
+
+func TestAuditor_Audit_Suppressions(t *testing.T) {
+	p := parser.NewSQLParser()
+	a := NewAuditor(nil, p)
+	a.Register(&NoWhereRule{})
+	a.Register(&SelectStarRule{})
+
+	segments := []model.SQLSegment{
+		{
+			SQL: "DELETE FROM tmp_staging",
+			Suppressions: []model.Suppression{
+				{Types: []string{"UNSAFE_DELETE"}, Reason: "staging table"},
+			},
+		},
+		{
+			SQL: "SELECT * FROM users",
+			Suppressions: []model.Suppression{
+				{Types: []string{"UNSAFE_DELETE"}},
+			},
+		},
+	}
+
+	issues, err := a.Audit(segments)
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+
+	active, suppressed := model.SplitSuppressed(issues)
+	if len(active) != 1 || active[0].Type != "SELECT_STAR" {
+		t.Errorf("Expected SELECT_STAR to stay active, got %+v", active)
+	}
+	if len(suppressed) != 1 || suppressed[0].SuppressReason != "staging table" {
+		t.Errorf("Expected UNSAFE_DELETE to be suppressed, got %+v", suppressed)
+	}
+}
+
+func TestAuditor_Audit_IssueLocation(t *testing.T) {
+	schema := &model.SchemaCtx{Tables: map[string]*model.Table{
+		"users": {
+			Name:    "users",
+			Columns: map[string]*model.Column{"name": {Name: "name", Type: "varchar(64)"}},
+		},
+	}}
+	p := parser.NewSQLParser()
+	a := NewAuditor(schema, p)
+	a.Register(&ImplicitConversionRule{})
+
+	// A multi-line query starting on line 10, column 12 of the source
+	seg := model.SQLSegment{
+		SQL:      "SELECT id\n\tFROM users\n\tWHERE id > 0\n\t  AND name = 123",
+		Location: model.Location{FilePath: "test.go", Line: 10, Column: 12, EndLine: 13, EndColumn: 18},
+	}
+	issues, err := a.Audit([]model.SQLSegment{seg, {SQL: "SELECT id FROM users WHERE AND", Location: model.Location{FilePath: "test.go", Line: 20, Column: 5}}})
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d", len(issues))
+	}
+
+	// The comparison, not the start of the query
+	if loc := issues[0].Location(); loc.Line != 13 || loc.Column != 8 {
+		t.Errorf("IMPLICIT_CONVERSION at %s, want test.go:13:8", loc)
+	}
+	// The parser error offset
+	if loc := issues[1].Location(); issues[1].Type != "PARSE_ERROR" || loc.Line != 20 || loc.Column != 32 {
+		t.Errorf("%s at %s, want PARSE_ERROR at test.go:20:32", issues[1].Type, loc)
+	}
+}
+
+func TestAuditor_Audit_TableSize(t *testing.T) {
+	schema := &model.SchemaCtx{Tables: map[string]*model.Table{
+		"settings": {Name: "settings", Columns: map[string]*model.Column{}, Rows: 200},
+		"orders":   {Name: "orders", Columns: map[string]*model.Column{}, Rows: 2000000000},
+		"users":    {Name: "users", Columns: map[string]*model.Column{}},
+	}}
+	a := NewAuditor(schema, parser.NewSQLParser())
+	a.Register(&SelectStarRule{})
+	a.Register(&NoWhereRule{})
+
+	tests := []struct {
+		sql  string
+		want []model.RiskLevel
+	}{
+		{"SELECT * FROM settings", []model.RiskLevel{model.RiskLevelSuggestion}},
+		{"SELECT * FROM orders", []model.RiskLevel{model.RiskLevelWarning}},
+		{"SELECT * FROM users", []model.RiskLevel{model.RiskLevelSuggestion}},
+		// users has no estimate, so the join is not known to be small
+		{"SELECT * FROM settings JOIN users", []model.RiskLevel{model.RiskLevelSuggestion}},
+		// NoWhereRule is not size-aware
+		{"DELETE FROM settings", []model.RiskLevel{model.RiskLevelFatal}},
+	}
+	for _, tt := range tests {
+		issues, err := a.Audit([]model.SQLSegment{{SQL: tt.sql}})
+		if err != nil {
+			t.Fatalf("Audit(%q) error = %v", tt.sql, err)
+		}
+		var got []model.RiskLevel
+		for _, issue := range issues {
+			got = append(got, issue.Level)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Audit(%q) levels = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+
+	// A WARNING on a small table becomes a SUGGESTION
+	a = NewAuditor(schema, parser.NewSQLParser())
+	a.Register(&NegativeQueryRule{})
+	issues, _ := a.Audit([]model.SQLSegment{{SQL: "SELECT id FROM settings WHERE name != 'x'"}})
+	if len(issues) != 1 || issues[0].Level != model.RiskLevelSuggestion || !strings.Contains(issues[0].Message, "settings has ~200 rows") {
+		t.Errorf("Expected a downgraded NEGATIVE_QUERY, got %+v", issues)
+	}
+}
+
+func TestAuditor_Audit_IssueTableSize(t *testing.T) {
+	id := &model.Column{Name: "id", BaseType: "bigint"}
+	userID := &model.Column{Name: "user_id", BaseType: "bigint"}
+	createdAt := &model.Column{Name: "created_at", BaseType: "datetime"}
+	byUser := &model.Index{Name: "idx_user", Columns: []string{"user_id"}, Cardinality: 100000000}
+	schema := &model.SchemaCtx{Tables: map[string]*model.Table{
+		"settings": {
+			Name:    "settings",
+			Columns: map[string]*model.Column{"id": id, "user_id": userID},
+			Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}, Unique: true}},
+			Rows:    200,
+		},
+		"orders": {
+			Name:    "orders",
+			Columns: map[string]*model.Column{"id": id, "user_id": userID, "created_at": createdAt},
+			Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}, Unique: true}, byUser},
+			Rows:    2000000000,
+		},
+	}}
+
+	tests := []struct {
+		name  string
+		rule  model.Rule
+		level model.RiskLevel // Set in the config, if not empty
+		sql   string
+		want  model.RiskLevel
+		note  string
+	}{
+		{
+			// The scanned table is small, even if the other one is not
+			name: "Issue table",
+			rule: &IndexMissRule{},
+			sql:  "SELECT o.id FROM orders o JOIN settings s ON s.id = o.id WHERE o.id = 1 AND s.user_id = 2",
+			want: model.RiskLevelSuggestion,
+			note: "table settings has ~200 rows",
+		},
+		{
+			// Each user has ~20 orders to sort
+			name: "Rows per key",
+			rule: &OrderByRule{},
+			sql:  "SELECT id FROM orders WHERE user_id = 1 ORDER BY created_at LIMIT 10",
+			want: model.RiskLevelSuggestion,
+			note: "~20 rows of table orders per key of idx_user",
+		},
+		{
+			name: "Whole table",
+			rule: &OrderByRule{},
+			sql:  "SELECT id FROM orders ORDER BY created_at LIMIT 10",
+			want: model.RiskLevelFatal,
+			note: "table orders has ~2000000000 rows",
+		},
+		{
+			name:  "Level set in the config",
+			rule:  &OrderByRule{},
+			level: model.RiskLevelWarning,
+			sql:   "SELECT id FROM orders ORDER BY created_at LIMIT 10",
+			want:  model.RiskLevelWarning,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuditor(schema, parser.NewSQLParser())
+			a.Register(tt.rule)
+			if tt.level != "" {
+				a.SetLevel(tt.rule.Name(), tt.level)
+			}
+			issues, err := a.Audit([]model.SQLSegment{{SQL: tt.sql}})
+			if err != nil {
+				t.Fatalf("Audit() error = %v", err)
+			}
+			if len(issues) != 1 {
+				t.Fatalf("Expected 1 issue, got %+v", issues)
+			}
+			if issues[0].Level != tt.want {
+				t.Errorf("Level = %s, want %s (%s)", issues[0].Level, tt.want, issues[0].Message)
+			}
+			if tt.note != "" && !strings.Contains(issues[0].Message, tt.note) {
+				t.Errorf("Message %q does not note %q", issues[0].Message, tt.note)
+			} else if tt.note == "" && strings.Contains(issues[0].Message, "~") {
+				t.Errorf("Message %q should not be adjusted", issues[0].Message)
+			}
+		})
+	}
+}
+
+func TestAuditor_Audit_TableResolution(t *testing.T) {
+	schema := model.NewSchemaCtx(1)
+	schema.AddTable(&model.Table{
+		Schema:  "shop",
+		Name:    "users",
+		Columns: map[string]*model.Column{"name": {Name: "name", Type: "varchar(64)"}},
+	})
+	a := NewAuditor(schema, parser.NewSQLParser())
+	a.Register(&ImplicitConversionRule{})
+
+	for _, sql := range []string{
+		"SELECT id FROM users WHERE name = 1",
+		"SELECT id FROM Users WHERE name = 1",
+		"SELECT id FROM SHOP.users WHERE name = 1",
+	} {
+		issues, err := a.Audit([]model.SQLSegment{{SQL: sql}})
+		if err != nil {
+			t.Fatalf("Audit(%q) error = %v", sql, err)
+		}
+		if len(issues) != 1 {
+			t.Errorf("Audit(%q) should resolve the table, got %d issues", sql, len(issues))
+		}
+	}
+
+	issues, _ := a.Audit([]model.SQLSegment{{SQL: "SELECT id FROM billing.users WHERE name = 1"}})
+	if len(issues) != 0 {
+		t.Errorf("billing.users is not shop.users, got %+v", issues)
+	}
+}
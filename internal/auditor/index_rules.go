@@ -4,26 +4,35 @@ import (
 	"fmt"
 	"sql-check/internal/model"
 	"sql-check/internal/parser"
+	"sort"
+	"strings"
 
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/opcode"
 )
 
-// IndexMissRule checks if WHERE usage aligns with available indexes
+// IndexMissRule checks if WHERE usage aligns with available indexes, and how
+// much of a composite index the conditions can use (leftmost prefix rule)
 type IndexMissRule struct{}
 
 func (r *IndexMissRule) Name() string { return "index_miss" }
 
+func (r *IndexMissRule) Description() string { return "WHERE conditions do not hit the leading column of any index, or use only part of a composite index" }
+
+func (r *IndexMissRule) DefaultLevel() model.RiskLevel { return model.RiskLevelWarning }
+
+func (r *IndexMissRule) SizeAware() {}
+
 func (r *IndexMissRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
-	var issues []model.Issue
+	return r.CheckBound(seg, node, parser.Bind(node, schema), schema)
+}
 
-	// 1. Identify Target Table Name and WHERE clause
-	// 1. Identify Target Table Name and WHERE clause
-	tables := parser.ExtractTableNames(node)
-	if len(tables) == 0 {
-		return nil, nil
-	}
-	tableName := tables[0] // Simplify: check strictly the first table found
+// CheckBound checks the WHERE columns of each table against the indexes of
+// that table, with columns resolved through aliases
+func (r *IndexMissRule) CheckBound(seg *model.SQLSegment, node ast.StmtNode, binding *parser.Binding, schema *model.SchemaCtx) ([]model.Issue, error) {
+	var issues []model.Issue
 
+	// 1. Identify the WHERE clause
 	var whereExpr ast.ExprNode
 	switch stmt := node.(type) {
 	case *ast.SelectStmt:
@@ -33,127 +42,288 @@ func (r *IndexMissRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *
 	case *ast.DeleteStmt:
 		whereExpr = stmt.Where
 	}
-
-	if tableName == "" || whereExpr == nil {
-		return nil, nil // Nothing to check or complex query
+	scope := binding.Scope(node)
+	if whereExpr == nil || scope == nil {
+		return nil, nil // Nothing to check
 	}
 
-	// 2. Lookup Table in Schema
-	table, ok := schema.Tables[tableName]
-	if !ok {
-		// Table not found in schema, maybe alias or missing schema
-		return nil, nil
+	// 2. Extract Columns used in WHERE, by the table they belong to
+	v := &columnVisitor{binding: binding, cols: make(map[*parser.TableRef]map[string]bool)}
+	whereExpr.Accept(v)
+	preds := predicates(binding, whereExpr)
+	wrapped := wrappedColumns(binding, whereExpr)
+
+	// 3. Check each table of the schema against its own indexes
+	for _, ref := range scope.BaseTables() {
+		usedCols := v.cols[ref]
+		if len(usedCols) == 0 {
+			continue // Not filtered by WHERE
+		}
+		table := ref.Table
+
+		if len(table.Indexes) == 0 {
+			issues = append(issues, model.Issue{
+				Type:       "NO_INDEXES_DEFINED",
+				Level:      model.RiskLevelWarning,
+				Message:    fmt.Sprintf("Table '%s' has no indexes defined.", table.Name),
+				Suggestion: "Add indexes to optimize queries.",
+				Segment:    *seg,
+				Table:      table,
+			})
+			continue
+		}
+
+		// At least ONE index must have its FIRST column used as is.
+		// This ensures we are not doing a full table scan (usually).
+		// Functional indexes are hit by their expression.
+		exprs := make(map[string]bool)
+		for _, w := range wrapped {
+			if w.col.Ref == ref {
+				exprs[parser.NormalizeExpr(w.wrapper)] = true
+			}
+		}
+		hasHit := false
+		for _, idx := range table.Indexes {
+			if len(idx.Columns) == 0 {
+				continue
+			}
+			first := idx.Columns[0]
+			if usedCols[strings.ToLower(first)] || (model.IsIndexExpression(first) && exprs[normalizedText(first)]) {
+				hasHit = true
+				break
+			}
+		}
+
+		if !hasHit {
+			if onlyNonSargable(ref, usedCols, wrapped, lookupColumns(ref, preds)) {
+				continue // Reported as NON_SARGABLE
+			}
+
+			// Construct error message with available indexes
+			var indexStr string
+			for _, idx := range table.Indexes {
+				indexStr += fmt.Sprintf("[%s(%v)] ", idx.Name, idx.Columns)
+			}
+
+			issues = append(issues, model.Issue{
+				Type:       "INDEX_MISS",
+				Level:      model.RiskLevelWarning,
+				Message:    fmt.Sprintf("Query on '%s' does not hit any index prefix. WHERE uses %v but available indexes are: %s", table.Name, mapKeys(usedCols), strings.TrimSpace(indexStr)),
+				Suggestion: "Ensure the WHERE clause filters on the leftmost column of an index.",
+				Segment:    *seg,
+				Table:      table,
+			})
+			continue
+		}
+
+		// 4. The index matching the most conditions may stop short of them
+		if issue, ok := partialIndexUse(seg, table, lookupColumns(ref, preds)); ok {
+			issues = append(issues, issue)
+		}
 	}
 
-	// 3. Extract Columns used in WHERE as simple Equality or Range
-	// We only care about columns that are candidates for indexing (e.g. A=1, A IN (..), A > 1)
-	usedCols := make(map[string]bool)
-	v := &columnVisitor{cols: usedCols}
-	whereExpr.Accept(v)
+	return issues, nil
+}
+
+// onlyNonSargable reports whether the columns of ref used in WHERE are all
+// wrapped columns that NonSargableRule reports: an index could look them up
+// if they stood alone
+func onlyNonSargable(ref *parser.TableRef, usedCols map[string]bool, wrapped []wrappedColumn, cols map[string]predicate) bool {
+	reported := make(map[string]bool)
+	for _, w := range wrapped {
+		if w.col.Ref != ref {
+			continue
+		}
+		if indexed, covered := indexedExpression(ref.Table, w.col.Name, parser.NormalizeExpr(w.wrapper), cols); indexed && !covered {
+			reported[strings.ToLower(w.col.Name)] = true
+		}
+	}
+	for name := range usedCols {
+		if !reported[name] {
+			return false
+		}
+	}
+	return true
+}
 
-	if len(usedCols) == 0 {
-		return nil, nil // No columns found in where? strange
+// lookupColumns returns the predicates of preds on the columns of ref by
+// lower-case column name, preferring equality over range conditions
+func lookupColumns(ref *parser.TableRef, preds []predicate) map[string]predicate {
+	cols := make(map[string]predicate)
+	for _, p := range preds {
+		if p.col.Ref != ref {
+			continue
+		}
+		name := strings.ToLower(p.col.Name)
+		if prev, ok := cols[name]; !ok || (prev.kind == predRange && p.kind == predEqual) {
+			cols[name] = p
+		}
 	}
+	return cols
+}
 
-	// 4. Check against Indexes
-	// Strategy: At least ONE index must have its FIRST column present in usedCols.
-	// This ensures we are not doing a full table scan (usually).
-	
-	hasHit := false
+// indexUse is how far the conditions of a query can use an index
+type indexUse struct {
+	index *model.Index
+	// usable is the number of leading columns the lookup is narrowed by:
+	// each equality condition extends it, a range condition ends it
+	usable int
+	// filtered is the number of columns of the index with a condition
+	filtered int
+	// stop is the predicate that ends the usable prefix, if it is a range
+	stop *predicate
+}
 
-	if len(table.Indexes) == 0 {
-		// Only primary key? The parser might not separate PK from indexes in my simplistic loader if implicit.
-		// My loader adds PK to indexes list, so this is fine.
-		issues = append(issues, model.Issue{
-			Type:       "NO_INDEXES_DEFINED",
-			Level:      model.RiskLevelWarning,
-			Message:    fmt.Sprintf("Table '%s' has no indexes defined.", tableName),
-			Suggestion: "Add indexes to optimize queries.",
-			Segment:    *seg,
-		})
-		return issues, nil
+func analyseIndex(idx *model.Index, cols map[string]predicate) indexUse {
+	use := indexUse{index: idx}
+	stopped := false
+	for _, col := range idx.Columns {
+		p, ok := cols[strings.ToLower(col)]
+		if !ok {
+			stopped = true
+			continue
+		}
+		use.filtered++
+		if stopped {
+			continue
+		}
+		use.usable++
+		if p.kind == predRange {
+			stopped = true
+			use.stop = &p
+		}
 	}
+	return use
+}
 
+// partialIndexUse reports the index with conditions on the most of its
+// columns if the lookup can only use some of them, because a range or a
+// column without a condition ends its usable prefix, and no other index
+// narrows the lookup as far or finds a single row
+func partialIndexUse(seg *model.SQLSegment, table *model.Table, cols map[string]predicate) (model.Issue, bool) {
+	var best indexUse
+	deepest := 0
 	for _, idx := range table.Indexes {
-		if len(idx.Columns) > 0 {
-			firstCol := idx.Columns[0]
-			if usedCols[firstCol] {
-				hasHit = true
-				break
-			}
+		use := analyseIndex(idx, cols)
+		if idx.Unique && use.usable == len(idx.Columns) && use.stop == nil {
+			return model.Issue{}, false // Single row lookup
+		}
+		if use.filtered > best.filtered || (use.filtered == best.filtered && use.usable > best.usable) {
+			best = use
 		}
+		if use.usable > deepest {
+			deepest = use.usable
+		}
+	}
+	if best.usable == 0 || best.filtered <= deepest {
+		return model.Issue{}, false
 	}
 
-	if !hasHit {
-		// Construct error message with available indexes
-		var indexStr string
-		for _, idx := range table.Indexes {
-			indexStr += fmt.Sprintf("[%s(%v)] ", idx.Name, idx.Columns)
+	// Equality columns first and one range column last can all be used
+	idx := best.index
+	var order, ranges []string
+	for _, col := range idx.Columns {
+		if p, ok := cols[strings.ToLower(col)]; ok {
+			if p.kind == predEqual {
+				order = append(order, col)
+			} else {
+				ranges = append(ranges, col)
+			}
 		}
-		
-		issues = append(issues, model.Issue{
-			Type:       "INDEX_MISS",
-			Level:      model.RiskLevelWarning,
-			Message:    fmt.Sprintf("Query on '%s' does not hit any index prefix. WHERE uses %v but available indexes are: %s", tableName, mapKeys(usedCols), indexStr),
-			Suggestion: "Ensure the WHERE clause filters on the leftmost column of an index.",
-			Segment:    *seg,
-		})
+	}
+	if len(ranges) > 0 {
+		order = append(order, ranges[0])
+	}
+	if len(order) <= best.usable {
+		return model.Issue{}, false // Several ranges, no order does better
 	}
 
-	return issues, nil
+	usableCols := idx.Columns[:best.usable]
+	var reason string
+	if best.stop != nil {
+		reason = fmt.Sprintf("the range condition on %s ends the usable prefix", best.stop.col.Name)
+	} else {
+		reason = fmt.Sprintf("%s has no condition", idx.Columns[best.usable])
+	}
+
+	issue := model.Issue{
+		Type:  "PARTIAL_INDEX_USE",
+		Level: model.RiskLevelSuggestion,
+		Message: fmt.Sprintf("Index %s(%s) on '%s' is used for %d of the %d columns the query filters on (effective key: %s): %s.",
+			idx.Name, strings.Join(idx.Columns, ", "), table.Name, best.usable, best.filtered, strings.Join(usableCols, ", "), reason),
+		Suggestion: fmt.Sprintf("Filter on the leading columns with equality (=, IN), or index %s(%s): equality columns first, a range column last.",
+			table.Name, strings.Join(order, ", ")),
+		Segment: *seg,
+		Table:   table,
+	}
+	if best.stop != nil {
+		issue.Offset = best.stop.expr.OriginTextPosition()
+	}
+	return issue, true
 }
 
+// mapKeys returns the keys of m, sorted
 func mapKeys(m map[string]bool) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
 		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 	return keys
 }
 
+// columnVisitor collects the columns of a WHERE clause by table source,
+// keyed by lower-case name. A column maps to true if it is used as is at
+// least once, false if only inside function calls, casts, arithmetic or
+// COLLATE, where an index on it cannot be used.
 type columnVisitor struct {
-	cols map[string]bool
+	binding *parser.Binding
+	cols    map[*parser.TableRef]map[string]bool
+	inFunc  int // Depth of the wrapping expressions being walked
 }
 
-func (v *columnVisitor) Enter(in ast.Node) (ast.Node, bool) {
-	if funcCall, ok := in.(*ast.FuncCallExpr); ok {
-		// Traverse function arguments to mark columns as used inside function
-		for _, arg := range funcCall.Args {
-			checkForColumn(arg, v.cols, true)
+// wrapsColumns reports whether an index on a column inside the expression
+// cannot be used
+func wrapsColumns(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.FuncCallExpr, *ast.FuncCastExpr, *ast.SetCollationExpr, *ast.UnaryOperationExpr:
+		return true
+	case *ast.BinaryOperationExpr:
+		switch n.Op {
+		case opcode.Plus, opcode.Minus, opcode.Mul, opcode.Div, opcode.IntDiv, opcode.Mod,
+			opcode.And, opcode.Or, opcode.Xor, opcode.LeftShift, opcode.RightShift:
+			return true
 		}
-		return in, true // Skip children as we handled them manually
 	}
+	return false
+}
 
-	if col, ok := in.(*ast.ColumnName); ok {
-		v.cols[col.Name.O] = false // false means "clean usage" (not in function)
+func (v *columnVisitor) Enter(in ast.Node) (ast.Node, bool) {
+	if wrapsColumns(in) {
+		v.inFunc++
+	}
+	switch n := in.(type) {
+	case *ast.SubqueryExpr:
+		return in, true // Columns of subqueries filter their own tables
+	case *ast.ColumnNameExpr:
+		bound := v.binding.Column(n)
+		if bound == nil {
+			return in, false
+		}
+		cols := v.cols[bound.Ref]
+		if cols == nil {
+			cols = make(map[string]bool)
+			v.cols[bound.Ref] = cols
+		}
+		name := strings.ToLower(bound.Name)
+		cols[name] = cols[name] || v.inFunc == 0
 	}
-	
 	return in, false
 }
 
 func (v *columnVisitor) Leave(in ast.Node) (ast.Node, bool) {
-	return in, true
-}
-
-func checkForColumn(node ast.Node, cols map[string]bool, inFunc bool) {
-	if col, ok := node.(*ast.ColumnName); ok {
-		if !inFunc {
-			cols[col.Name.O] = true
-		}
-	} else {
-		// Manual recursion for common expression types used in args
-		// This is a simplified traversal since we don't have the visitor here
-		switch expr := node.(type) {
-		case *ast.BinaryOperationExpr:
-			checkForColumn(expr.L, cols, inFunc)
-			checkForColumn(expr.R, cols, inFunc)
-		case *ast.ParenthesesExpr:
-			checkForColumn(expr.Expr, cols, inFunc)
-		case *ast.FuncCallExpr:
-			for _, arg := range expr.Args {
-				checkForColumn(arg, cols, true) // Nested function is still inFunc=true
-			}
-		// Add other types as needed
-		}
+	if wrapsColumns(in) {
+		v.inFunc--
 	}
+	return in, true
 }
@@ -0,0 +1,254 @@
+package auditor
+
+import (
+	"fmt"
+	"strings"
+
+	"sql-check/internal/model"
+	"sql-check/internal/parser"
+
+	"github.com/pingcap/tidb/parser/ast"
+)
+
+// JoinIndexRule checks that each table of a join that is read once per row
+// of the tables before it can be accessed through an index
+type JoinIndexRule struct{}
+
+func (r *JoinIndexRule) Name() string { return "join_index_miss" }
+
+func (r *JoinIndexRule) Description() string {
+	return "Joined table has no index for its join columns and is scanned for every outer row"
+}
+
+func (r *JoinIndexRule) DefaultLevel() model.RiskLevel { return model.RiskLevelWarning }
+
+func (r *JoinIndexRule) SizeAware() {}
+
+func (r *JoinIndexRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
+	return r.CheckBound(seg, node, parser.Bind(node, schema), schema)
+}
+
+// CheckBound looks at every query block with a join. For each joined table,
+// the columns its ON and WHERE conditions can look it up by must include
+// the leading column of one of its indexes.
+//
+// The right table of a LEFT JOIN (left of a RIGHT JOIN, right of a
+// STRAIGHT_JOIN) is always read after the other side. Inner joins may be
+// reordered by the optimizer, so one table without a usable index can
+// drive the join; the ones after it are reported.
+func (r *JoinIndexRule) CheckBound(seg *model.SQLSegment, node ast.StmtNode, binding *parser.Binding, schema *model.SchemaCtx) ([]model.Issue, error) {
+	var issues []model.Issue
+
+	for _, block := range queryBlocks(node) {
+		from, where := blockClauses(block)
+		scope := binding.Scope(block)
+		if from == nil || scope == nil || len(scope.Tables) < 2 {
+			continue
+		}
+
+		driven := make(map[*parser.TableRef]bool)
+		preds := predicates(binding, where)
+		preds = append(preds, joinConditions(binding, scope, from.TableRefs, driven)...)
+
+		inScope := make(map[*parser.TableRef]bool, len(scope.Tables))
+		for _, ref := range scope.Tables {
+			inScope[ref] = true
+		}
+		joins := make(map[*parser.TableRef][]predicate)
+		access := make(map[*parser.TableRef]map[string]bool)
+		for _, p := range preds {
+			ref := p.col.Ref
+			if !inScope[ref] {
+				continue // Outer column of a correlated subquery
+			}
+			if p.other != nil && inScope[p.other.Ref] {
+				joins[ref] = append(joins[ref], p)
+			}
+			if access[ref] == nil {
+				access[ref] = make(map[string]bool)
+			}
+			access[ref][strings.ToLower(p.col.Name)] = true
+		}
+
+		var unindexed []*parser.TableRef
+		for _, ref := range scope.BaseTables() {
+			if len(joins[ref]) == 0 || hitsIndexPrefix(ref.Table, access[ref]) {
+				continue // Not joined by a condition (cross join), or indexed
+			}
+			if driven[ref] {
+				issues = append(issues, r.issue(seg, ref, joins[ref]))
+			} else {
+				unindexed = append(unindexed, ref)
+			}
+		}
+		for i, ref := range unindexed {
+			if i > 0 {
+				issues = append(issues, r.issue(seg, ref, joins[ref]))
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+func (r *JoinIndexRule) issue(seg *model.SQLSegment, ref *parser.TableRef, joins []predicate) model.Issue {
+	cols := make(map[string]bool)
+	outer := make(map[string]bool)
+	for _, p := range joins {
+		cols[strings.ToLower(p.col.Name)] = true
+		name := p.other.Ref.Name
+		if p.other.Ref.Table != nil {
+			name = p.other.Ref.Table.Name
+		}
+		outer["'"+name+"'"] = true
+	}
+
+	issue := model.Issue{
+		Type:  "JOIN_INDEX_MISS",
+		Level: model.RiskLevelWarning,
+		Message: fmt.Sprintf("Joined table '%s' is scanned for every row of %s: no index starts with its join columns %v.",
+			ref.Table.Name, strings.Join(mapKeys(outer), ", "), mapKeys(cols)),
+		Suggestion: fmt.Sprintf("Add an index on %s(%s) so that each outer row is an index lookup.", ref.Table.Name, joins[0].col.Name),
+		Segment:    *seg,
+		Table:      ref.Table,
+	}
+	if joins[0].expr != nil {
+		issue.Offset = joins[0].expr.OriginTextPosition()
+	}
+	return issue
+}
+
+// hitsIndexPrefix reports whether cols, lower case, include the leading
+// column of an index of the table
+func hitsIndexPrefix(table *model.Table, cols map[string]bool) bool {
+	for _, idx := range table.Indexes {
+		if len(idx.Columns) > 0 && cols[strings.ToLower(idx.Columns[0])] {
+			return true
+		}
+	}
+	return false
+}
+
+// joinConditions returns the predicates of the ON and USING clauses of a
+// FROM clause and marks the tables that are read after the other side of
+// their join in driven
+func joinConditions(binding *parser.Binding, scope *parser.Scope, node ast.ResultSetNode, driven map[*parser.TableRef]bool) []predicate {
+	if ts, ok := node.(*ast.TableSource); ok {
+		node = ts.Source
+	}
+	join, ok := node.(*ast.Join)
+	if !ok {
+		return nil
+	}
+
+	var preds []predicate
+	if join.Left != nil {
+		preds = append(preds, joinConditions(binding, scope, join.Left, driven)...)
+	}
+	if join.Right == nil {
+		return preds
+	}
+	preds = append(preds, joinConditions(binding, scope, join.Right, driven)...)
+
+	switch {
+	case join.Tp == ast.LeftJoin || join.StraightJoin:
+		for _, ref := range sourceRefs(scope, join.Right) {
+			driven[ref] = true
+		}
+	case join.Tp == ast.RightJoin:
+		for _, ref := range sourceRefs(scope, join.Left) {
+			driven[ref] = true
+		}
+	}
+
+	if join.On != nil {
+		preds = append(preds, predicates(binding, join.On.Expr)...)
+	}
+	for _, name := range join.Using {
+		left := refWithColumn(sourceRefs(scope, join.Left), name.Name.O)
+		right := refWithColumn(sourceRefs(scope, join.Right), name.Name.O)
+		if left == nil || right == nil {
+			continue
+		}
+		preds = append(preds,
+			predicate{col: left, kind: predEqual, other: right},
+			predicate{col: right, kind: predEqual, other: left})
+	}
+	return preds
+}
+
+// sourceRefs returns the table sources of scope that are part of node
+func sourceRefs(scope *parser.Scope, node ast.ResultSetNode) []*parser.TableRef {
+	switch n := node.(type) {
+	case *ast.Join:
+		refs := sourceRefs(scope, n.Left)
+		if n.Right != nil {
+			refs = append(refs, sourceRefs(scope, n.Right)...)
+		}
+		return refs
+	case *ast.TableSource:
+		if join, ok := n.Source.(*ast.Join); ok {
+			return sourceRefs(scope, join)
+		}
+		for _, ref := range scope.Tables {
+			if ref.Source == n {
+				return []*parser.TableRef{ref}
+			}
+		}
+	}
+	return nil
+}
+
+// refWithColumn returns the column of the first table source that has it,
+// as USING resolves it
+func refWithColumn(refs []*parser.TableRef, name string) *parser.BoundColumn {
+	for _, ref := range refs {
+		if ref.Derived {
+			if col, ok := ref.Columns[strings.ToLower(name)]; ok {
+				return &parser.BoundColumn{Ref: ref, Name: name, Column: col}
+			}
+		} else if ref.Table != nil {
+			if col := parser.FindColumn(ref.Table, name); col != nil {
+				return &parser.BoundColumn{Ref: ref, Name: name, Column: col}
+			}
+		}
+	}
+	return nil
+}
+
+// queryBlocks returns the SELECT, UPDATE and DELETE blocks of a statement,
+// outermost first
+func queryBlocks(node ast.Node) []ast.Node {
+	v := &blockCollector{}
+	node.Accept(v)
+	return v.blocks
+}
+
+type blockCollector struct {
+	blocks []ast.Node
+}
+
+func (v *blockCollector) Enter(in ast.Node) (ast.Node, bool) {
+	switch in.(type) {
+	case *ast.SelectStmt, *ast.UpdateStmt, *ast.DeleteStmt:
+		v.blocks = append(v.blocks, in)
+	}
+	return in, false
+}
+
+func (v *blockCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// blockClauses returns the table references and WHERE clause of a query block
+func blockClauses(block ast.Node) (*ast.TableRefsClause, ast.ExprNode) {
+	switch n := block.(type) {
+	case *ast.SelectStmt:
+		return n.From, n.Where
+	case *ast.UpdateStmt:
+		return n.TableRefs, n.Where
+	case *ast.DeleteStmt:
+		return n.TableRefs, n.Where
+	}
+	return nil, nil
+}
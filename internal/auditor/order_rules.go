@@ -0,0 +1,429 @@
+package auditor
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"sql-check/internal/model"
+	"sql-check/internal/parser"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/parser/test_driver"
+)
+
+// OrderByRule detects ORDER BY and GROUP BY clauses that cannot be read in
+// the order of the index the table is read through, so that MySQL sorts
+// the rows (filesort) or groups them in a temporary table
+type OrderByRule struct{}
+
+func (r *OrderByRule) Name() string { return "filesort" }
+
+func (r *OrderByRule) Description() string {
+	return "ORDER BY or GROUP BY is not served by the index the table is read through (filesort, temporary table)"
+}
+
+func (r *OrderByRule) DefaultLevel() model.RiskLevel { return model.RiskLevelWarning }
+
+func (r *OrderByRule) SizeAware() {}
+
+func (r *OrderByRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
+	return r.CheckBound(seg, node, parser.Bind(node, schema), schema)
+}
+
+// CheckBound checks each SELECT with ORDER BY or GROUP BY. The table is
+// taken to be read through the index whose leading columns the WHERE and ON
+// conditions narrow the most; without conditions any index can be scanned
+// in order. It serves the ordering if the sorted columns follow its usable
+// prefix, skipping columns compared with a constant, in the same or the
+// opposite direction throughout; secondary indexes end with the primary
+// key columns, as in InnoDB. A filesort with a LIMIT is a warning: all
+// matching rows are read and sorted to return a few.
+func (r *OrderByRule) CheckBound(seg *model.SQLSegment, node ast.StmtNode, binding *parser.Binding, schema *model.SchemaCtx) ([]model.Issue, error) {
+	var issues []model.Issue
+
+	for _, block := range queryBlocks(node) {
+		sel, ok := block.(*ast.SelectStmt)
+		scope := binding.Scope(block)
+		if !ok || scope == nil || (sel.OrderBy == nil && sel.GroupBy == nil) {
+			continue
+		}
+
+		q := &orderQuery{binding: binding, scope: scope, driven: make(map[*parser.TableRef]bool)}
+		q.preds = predicates(binding, sel.Where)
+		if sel.From != nil {
+			q.preds = append(q.preds, joinConditions(binding, scope, sel.From.TableRefs, q.driven)...)
+		}
+		var limit int64 = -1
+		if sel.Limit != nil {
+			if count, ok := limitValue(sel.Limit.Count); ok {
+				limit = count
+			}
+		}
+
+		if sel.GroupBy != nil {
+			if res := q.check(sel.GroupBy.Items, true); !res.served {
+				issues = append(issues, model.Issue{
+					Type:  "TEMPORARY_TABLE",
+					Level: model.RiskLevelSuggestion,
+					Message: fmt.Sprintf("GROUP BY %s cannot be read in index order: %s. Probable temporary table.",
+						byItemsText(sel.GroupBy.Items), res.reason),
+					Suggestion: res.suggestion("so that groups are read one after the other", limit),
+					Segment:    *seg,
+					Offset:     sel.GroupBy.Items[0].Expr.OriginTextPosition(),
+					Table:      res.source,
+					Index:      res.lookup,
+				})
+			}
+		}
+
+		if sel.OrderBy == nil {
+			continue
+		}
+		var res orderCheck
+		if sel.GroupBy != nil {
+			res = q.checkOrderOfGroups(sel.OrderBy.Items, sel.GroupBy.Items)
+		} else {
+			res = q.check(sel.OrderBy.Items, false)
+		}
+		if res.served {
+			continue
+		}
+		issue := model.Issue{
+			Type:  "FILESORT",
+			Level: model.RiskLevelSuggestion,
+			Message: fmt.Sprintf("ORDER BY %s cannot be read in index order: %s. Probable filesort of all matching rows.",
+				byItemsText(sel.OrderBy.Items), res.reason),
+			Suggestion: res.suggestion("so that rows are read in order", limit),
+			Segment:    *seg,
+			Offset:     sel.OrderBy.Items[0].Expr.OriginTextPosition(),
+			Table:      res.source,
+			Index:      res.lookup,
+		}
+		if limit >= 0 {
+			issue.Level = model.RiskLevelWarning
+			issue.Message = fmt.Sprintf("ORDER BY %s cannot be read in index order: %s. Probable filesort of all matching rows before LIMIT %d.",
+				byItemsText(sel.OrderBy.Items), res.reason, limit)
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// orderQuery is a query block whose ordering is checked
+type orderQuery struct {
+	binding *parser.Binding
+	scope   *parser.Scope
+	preds   []predicate // WHERE and ON conditions
+	driven  map[*parser.TableRef]bool
+}
+
+// orderCheck is the outcome of checking an ordering against the indexes
+type orderCheck struct {
+	served bool
+	reason string // Why it is not served
+
+	// table and columns make up the index that would serve it, if any
+	table   string
+	columns []string
+
+	// source is the table whose rows are sorted, lookup the index whose
+	// full key the conditions look up, if any
+	source *model.Table
+	lookup *model.Index
+}
+
+func (c orderCheck) suggestion(purpose string, limit int64) string {
+	if c.table == "" {
+		return "Sort by columns of the first table read, in the order of one of its indexes, or sort in the application."
+	}
+	s := fmt.Sprintf("Add an index on %s(%s) %s", c.table, strings.Join(c.columns, ", "), purpose)
+	if limit >= 0 {
+		s += ", which also lets the LIMIT stop the scan early"
+	}
+	return s + "."
+}
+
+// sortKey is a column of an ORDER BY or GROUP BY clause
+type sortKey struct {
+	col  *parser.BoundColumn
+	desc bool
+}
+
+// check tells whether the indexes of the table sorted by can return rows
+// in the order of items. For GROUP BY (grouping), the direction and the
+// order of the columns do not matter.
+func (q *orderQuery) check(items []*ast.ByItem, grouping bool) orderCheck {
+	var keys []sortKey
+	var ref *parser.TableRef
+	for _, item := range items {
+		switch item.Expr.(type) {
+		case *test_driver.ValueExpr:
+			continue // ORDER BY NULL
+		case *ast.PositionExpr:
+			return orderCheck{served: true} // Select list position, not checked
+		}
+		col := boundColumn(q.binding, item.Expr)
+		if col == nil {
+			if _, ok := item.Expr.(*ast.ColumnNameExpr); ok {
+				return orderCheck{served: true} // Alias of the select list, not checked
+			}
+			return orderCheck{reason: fmt.Sprintf("%s is an expression", parser.NormalizeExpr(item.Expr))}
+		}
+		if ref != nil && col.Ref != ref {
+			return orderCheck{reason: "it sorts by columns of several tables"}
+		}
+		ref = col.Ref
+		keys = append(keys, sortKey{col: col, desc: item.Desc})
+	}
+	if ref == nil || ref.Table == nil {
+		return orderCheck{served: true}
+	}
+	table := ref.Table
+	if q.driven[ref] {
+		return orderCheck{reason: fmt.Sprintf("'%s' is not the first table of the join", table.Name)}
+	}
+
+	// Columns compared with a constant do not change the order
+	lookup := lookupColumns(ref, q.preds)
+	constants := make(map[string]bool)
+	for name, p := range lookup {
+		if isConstantEquality(p) {
+			constants[name] = true
+		}
+	}
+	var sorted []sortKey
+	for _, key := range keys {
+		if !constants[strings.ToLower(key.col.Name)] {
+			sorted = append(sorted, key)
+		}
+	}
+	if len(sorted) == 0 {
+		return orderCheck{served: true}
+	}
+
+	// The index the table is read through
+	var uses []indexUse
+	deepest := 0
+	for _, idx := range table.Indexes {
+		use := analyseIndex(idx, lookup)
+		if idx.Unique && use.usable == len(idx.Columns) && use.stop == nil {
+			return orderCheck{served: true} // Single row
+		}
+		uses = append(uses, use)
+		if use.usable > deepest {
+			deepest = use.usable
+		}
+	}
+	mixed := false
+	var chosen *model.Index
+	for _, use := range uses {
+		if use.usable != deepest {
+			continue
+		}
+		ok, mixedDirections := indexServes(withPrimaryKey(table, use.index), sorted, constants, grouping)
+		if ok {
+			return orderCheck{served: true}
+		}
+		mixed = mixed || mixedDirections
+		if chosen == nil {
+			chosen = use.index
+		}
+	}
+
+	// Constant columns first, then the sorted ones
+	var columns []string
+	for name := range constants {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	for _, key := range sorted {
+		col := key.col.Name
+		if key.desc && mixed {
+			col += " DESC"
+		}
+		columns = append(columns, col)
+	}
+	res := orderCheck{table: table.Name, columns: columns, source: table}
+	if chosen != nil && deepest == len(chosen.Columns) {
+		res.lookup = chosen
+	}
+	switch {
+	case mixed:
+		res.reason = fmt.Sprintf("it mixes ASC and DESC, unlike the indexes of '%s'", table.Name)
+	case deepest > 0:
+		res.reason = fmt.Sprintf("'%s' is read through index %s(%s), looked up by %s, which does not return rows in that order",
+			table.Name, chosen.Name, strings.Join(chosen.Columns, ", "), lookupConditions(q.binding, chosen.Columns[:deepest], lookup))
+	default:
+		res.reason = fmt.Sprintf("no index of '%s' starts with these columns", table.Name)
+	}
+	return res
+}
+
+// lookupConditions describes the conditions an index is looked up by, given
+// its leading columns they narrow: join conditions first, then the others
+func lookupConditions(binding *parser.Binding, columns []string, lookup map[string]predicate) string {
+	var joins, filters []string
+	for _, col := range columns {
+		p := lookup[strings.ToLower(col)]
+		text := "USING (" + col + ")"
+		if p.expr != nil {
+			text = exprText(binding, p.expr)
+		}
+		if p.other != nil {
+			joins = append(joins, text)
+		} else {
+			filters = append(filters, text)
+		}
+	}
+
+	var parts []string
+	if len(joins) > 0 {
+		parts = append(parts, "the join condition "+strings.Join(joins, " AND "))
+	}
+	if len(filters) > 0 {
+		parts = append(parts, strings.Join(filters, " AND "))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// checkOrderOfGroups tells whether sorting grouped rows needs a sort of its
+// own: it does not if the ORDER BY columns are the leading GROUP BY
+// columns, in one direction
+func (q *orderQuery) checkOrderOfGroups(order, group []*ast.ByItem) orderCheck {
+	again := orderCheck{reason: "it differs from the GROUP BY, so the groups are sorted again"}
+	if len(order) > len(group) {
+		return again
+	}
+	for i, item := range order {
+		col := boundColumn(q.binding, item.Expr)
+		groupCol := boundColumn(q.binding, group[i].Expr)
+		if col == nil || groupCol == nil {
+			if _, ok := item.Expr.(*test_driver.ValueExpr); ok {
+				return orderCheck{served: true} // ORDER BY NULL
+			}
+			return again
+		}
+		if col.Ref != groupCol.Ref || !strings.EqualFold(col.Name, groupCol.Name) || item.Desc != order[0].Desc {
+			return again
+		}
+	}
+	return orderCheck{served: true} // Sorted as grouped, see the GROUP BY check
+}
+
+// indexServes tells whether reading idx returns rows in the order of keys,
+// key parts compared with constants being skipped. mixed is set if it
+// would, but for the directions of the keys.
+func indexServes(idx *model.Index, keys []sortKey, constants map[string]bool, grouping bool) (ok, mixed bool) {
+	pending := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		pending[strings.ToLower(key.col.Name)] = true
+	}
+
+	pos := 0
+	reverse := false
+	for i, part := range idx.Columns {
+		if pos == len(keys) {
+			break
+		}
+		name := strings.ToLower(part)
+		switch {
+		case grouping && pending[name]:
+			delete(pending, name)
+			pos++
+		case !grouping && strings.EqualFold(part, keys[pos].col.Name):
+			r := keys[pos].desc != idx.IsDescending(i)
+			if pos > 0 && r != reverse {
+				mixed = true
+			}
+			reverse = r
+			pos++
+		case constants[name]:
+		default:
+			return false, false
+		}
+	}
+	if pos < len(keys) {
+		return false, false
+	}
+	return !mixed, mixed
+}
+
+// withPrimaryKey returns a secondary index with the primary key columns it
+// is not made of appended, as InnoDB stores them in every index entry
+func withPrimaryKey(table *model.Table, idx *model.Index) *model.Index {
+	var pk *model.Index
+	for _, i := range table.Indexes {
+		if i.Name == "PRIMARY" {
+			pk = i
+		}
+	}
+	if pk == nil || pk == idx {
+		return idx
+	}
+
+	extended := *idx
+	extended.Columns = append([]string(nil), idx.Columns...)
+	for _, col := range pk.Columns {
+		if !slices.ContainsFunc(idx.Columns, func(c string) bool { return strings.EqualFold(c, col) }) {
+			extended.Columns = append(extended.Columns, col)
+		}
+	}
+	return &extended
+}
+
+// isConstantEquality reports whether a predicate fixes its column to a
+// single value
+func isConstantEquality(p predicate) bool {
+	if p.other != nil || p.kind != predEqual {
+		return false
+	}
+	switch e := p.expr.(type) {
+	case *ast.BinaryOperationExpr:
+		return e.Op == opcode.EQ || e.Op == opcode.NullEQ
+	case *ast.IsNullExpr:
+		return true
+	case *ast.PatternInExpr:
+		return len(e.List) == 1
+	}
+	return false
+}
+
+// byItemsText is the text of ORDER BY or GROUP BY items, for messages
+func byItemsText(items []*ast.ByItem) string {
+	texts := make([]string, 0, len(items))
+	for _, item := range items {
+		text := parser.NormalizeExpr(item.Expr)
+		if col, ok := item.Expr.(*ast.ColumnNameExpr); ok {
+			text = col.Name.Name.O
+		}
+		if item.Desc {
+			text += " DESC"
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, ", ")
+}
+
+// limitValue returns the value of a LIMIT count or offset, false if it is
+// not a literal. The parser reads them as unsigned integers.
+func limitValue(expr ast.ExprNode) (int64, bool) {
+	val, ok := expr.(*test_driver.ValueExpr)
+	if !ok {
+		return 0, false
+	}
+	switch v := val.GetValue().(type) {
+	case int64:
+		return v, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return math.MaxInt64, true
+		}
+		return int64(v), true
+	}
+	return 0, false
+}
@@ -16,6 +16,27 @@ type DeepPaginationRule struct {
 
 func (r *DeepPaginationRule) Name() string { return "deep_pagination" }
 
+func (r *DeepPaginationRule) Description() string { return "LIMIT with a large OFFSET scans and discards many rows" }
+
+func (r *DeepPaginationRule) DefaultLevel() model.RiskLevel { return model.RiskLevelWarning }
+
+func (r *DeepPaginationRule) SizeAware() {}
+
+// Configure accepts the "threshold" parameter (maximum allowed OFFSET)
+func (r *DeepPaginationRule) Configure(params map[string]interface{}) error {
+	if err := checkParams(params, "threshold"); err != nil {
+		return err
+	}
+	threshold, ok, err := intParam(params, "threshold")
+	if err != nil {
+		return err
+	}
+	if ok {
+		r.Threshold = threshold
+	}
+	return nil
+}
+
 func (r *DeepPaginationRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
 	var issues []model.Issue
 	limitThreshold := r.Threshold
@@ -26,16 +47,15 @@ func (r *DeepPaginationRule) Check(seg *model.SQLSegment, node ast.StmtNode, sch
 	// Helper to check Limit node
 	checkLimit := func(limit *ast.Limit) {
 		if limit != nil && limit.Offset != nil {
-			if val, ok := limit.Offset.(*test_driver.ValueExpr); ok {
-				if intVal, ok := val.GetValue().(int64); ok && intVal > limitThreshold {
-					issues = append(issues, model.Issue{
-						Type:       "DEEP_PAGINATION",
-						Level:      model.RiskLevelWarning,
-						Message:    "Deep pagination detected (High Offset)",
-						Suggestion: "Use keyset pagination (WHERE id > last_id) instead of OFFSET.",
-						Segment:    *seg,
-					})
-				}
+			if offset, ok := limitValue(limit.Offset); ok && offset > limitThreshold {
+				issues = append(issues, model.Issue{
+					Type:       "DEEP_PAGINATION",
+					Level:      model.RiskLevelWarning,
+					Message:    "Deep pagination detected (High Offset)",
+					Suggestion: "Use keyset pagination (WHERE id > last_id) instead of OFFSET.",
+					Segment:    *seg,
+					Offset:     limit.Offset.OriginTextPosition(),
+				})
 			}
 		}
 	}
@@ -55,6 +75,12 @@ type NegativeQueryRule struct{}
 
 func (r *NegativeQueryRule) Name() string { return "negative_query" }
 
+func (r *NegativeQueryRule) Description() string { return "Negative conditions (!=, NOT IN) and leading wildcards in LIKE prevent index use" }
+
+func (r *NegativeQueryRule) DefaultLevel() model.RiskLevel { return model.RiskLevelWarning }
+
+func (r *NegativeQueryRule) SizeAware() {}
+
 func (r *NegativeQueryRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
 	var issues []model.Issue
 
@@ -78,6 +104,7 @@ func (v *negativeVisitor) Enter(in ast.Node) (ast.Node, bool) {
 			Message:    "Avoid using NOT IN",
 			Suggestion: "Use NOT EXISTS or LEFT JOIN ... IS NULL which are often better optimized.",
 			Segment:    *v.seg,
+			Offset:     pattern.OriginTextPosition(),
 		})
 	}
 
@@ -89,6 +116,7 @@ func (v *negativeVisitor) Enter(in ast.Node) (ast.Node, bool) {
 				Message:    "Avoid using != (Not Equal)",
 				Suggestion: "Negative comparison often prevents index usage.",
 				Segment:    *v.seg,
+				Offset:     binOp.OriginTextPosition(),
 			})
 		}
 	}
@@ -104,6 +132,7 @@ func (v *negativeVisitor) Enter(in ast.Node) (ast.Node, bool) {
 					Message:    "LIKE query with leading wildcard",
 					Suggestion: "Leading wildcards confuse the optimizer and prevent index usage (Full Table Scan).",
 					Segment:    *v.seg,
+					Offset:     pattern.OriginTextPosition(),
 				})
 			}
 		}
@@ -0,0 +1,162 @@
+package auditor
+
+import (
+	"strings"
+
+	"sql-check/internal/parser"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/parser/test_driver"
+)
+
+// predicateKind tells how an index on the column of a predicate can be used
+type predicateKind int
+
+const (
+	// predEqual is a lookup of single values: =, <=>, IN, IS NULL
+	predEqual predicateKind = iota
+	// predRange is a scan of a range: <, >, BETWEEN, LIKE 'abc%'
+	predRange
+)
+
+// predicate is a condition an index on col can serve
+type predicate struct {
+	col  *parser.BoundColumn
+	kind predicateKind
+	// other is a column the value depends on, as in a join condition
+	// a.x = b.y; nil when col is compared with constants
+	other *parser.BoundColumn
+	expr  ast.ExprNode // The whole condition
+}
+
+// predicates returns the index-usable conditions of a WHERE or ON clause.
+// Only the terms of the top-level AND are considered, as a disjunction
+// cannot be served by a single index lookup.
+func predicates(binding *parser.Binding, expr ast.ExprNode) []predicate {
+	var preds []predicate
+	for _, term := range conjuncts(expr) {
+		preds = append(preds, termPredicates(binding, term)...)
+	}
+	return preds
+}
+
+// conjuncts splits a condition into the terms of its top-level AND
+func conjuncts(expr ast.ExprNode) []ast.ExprNode {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+	case *ast.ParenthesesExpr:
+		return conjuncts(e.Expr)
+	case *ast.BinaryOperationExpr:
+		if e.Op == opcode.LogicAnd {
+			return append(conjuncts(e.L), conjuncts(e.R)...)
+		}
+	}
+	return []ast.ExprNode{expr}
+}
+
+func termPredicates(binding *parser.Binding, term ast.ExprNode) []predicate {
+	switch e := term.(type) {
+	case *ast.BinaryOperationExpr:
+		var kind predicateKind
+		switch e.Op {
+		case opcode.EQ, opcode.NullEQ:
+			kind = predEqual
+		case opcode.LT, opcode.LE, opcode.GT, opcode.GE:
+			kind = predRange
+		default:
+			return nil
+		}
+		var preds []predicate
+		if p, ok := comparison(binding, e.L, e.R, kind, term); ok {
+			preds = append(preds, p)
+		}
+		if p, ok := comparison(binding, e.R, e.L, kind, term); ok {
+			preds = append(preds, p)
+		}
+		return preds
+	case *ast.PatternInExpr:
+		if col := boundColumn(binding, e.Expr); col != nil && !e.Not {
+			return []predicate{{col: col, kind: predEqual, expr: term}}
+		}
+	case *ast.IsNullExpr:
+		if col := boundColumn(binding, e.Expr); col != nil && !e.Not {
+			return []predicate{{col: col, kind: predEqual, expr: term}}
+		}
+	case *ast.BetweenExpr:
+		if col := boundColumn(binding, e.Expr); col != nil && !e.Not {
+			return []predicate{{col: col, kind: predRange, expr: term}}
+		}
+	case *ast.PatternLikeOrIlikeExpr:
+		col := boundColumn(binding, e.Expr)
+		pattern, ok := e.Pattern.(*test_driver.ValueExpr)
+		if col == nil || e.Not || !e.IsLike || !ok {
+			return nil
+		}
+		// Only a constant prefix can be looked up
+		if s := pattern.GetString(); s != "" && !strings.ContainsAny(s[:1], "%_") {
+			return []predicate{{col: col, kind: predRange, expr: term}}
+		}
+	}
+	return nil
+}
+
+// comparison makes a predicate of col <op> value if col is a bare column
+// and value does not depend on col's own table
+func comparison(binding *parser.Binding, colExpr, value ast.ExprNode, kind predicateKind, term ast.ExprNode) (predicate, bool) {
+	col := boundColumn(binding, colExpr)
+	if col == nil {
+		return predicate{}, false
+	}
+	p := predicate{col: col, kind: kind, expr: term}
+	selfDependent := false
+	value.Accept(&columnFinder{fn: func(expr *ast.ColumnNameExpr) {
+		bc := binding.Column(expr)
+		switch {
+		case bc == nil:
+		case bc.Ref == col.Ref:
+			selfDependent = true
+		case p.other == nil:
+			p.other = bc
+		}
+	}})
+	if selfDependent {
+		return predicate{}, false // a.x = a.y + 1 cannot be looked up
+	}
+	return p, true
+}
+
+// boundColumn returns what expr resolves to if it is a bare column reference
+func boundColumn(binding *parser.Binding, expr ast.ExprNode) *parser.BoundColumn {
+	for {
+		paren, ok := expr.(*ast.ParenthesesExpr)
+		if !ok {
+			break
+		}
+		expr = paren.Expr
+	}
+	if colExpr, ok := expr.(*ast.ColumnNameExpr); ok {
+		return binding.Column(colExpr)
+	}
+	return nil
+}
+
+// columnFinder calls fn for each column reference outside of subqueries
+type columnFinder struct {
+	fn func(expr *ast.ColumnNameExpr)
+}
+
+func (v *columnFinder) Enter(in ast.Node) (ast.Node, bool) {
+	switch n := in.(type) {
+	case *ast.SubqueryExpr:
+		return in, true
+	case *ast.ColumnNameExpr:
+		v.fn(n)
+	}
+	return in, false
+}
+
+func (v *columnFinder) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
@@ -0,0 +1,82 @@
+package auditor
+
+import (
+	"fmt"
+	"sql-check/internal/model"
+	"sql-check/internal/parser"
+
+	"github.com/pingcap/tidb/parser/ast"
+)
+
+// Configurable is implemented by rules that take parameters from the config file
+type Configurable interface {
+	Configure(params map[string]interface{}) error
+}
+
+// SizeAware marks rules whose findings matter in proportion to the size of
+// the table, such as full scans. With table statistics, their issues are
+// downgraded on small tables and escalated on large ones, unless the level
+// of the rule is set in the config.
+type SizeAware interface {
+	SizeAware()
+}
+
+// BoundRule is implemented by rules that need table aliases and columns
+// resolved. The auditor binds each statement once and calls CheckBound
+// instead of Check.
+type BoundRule interface {
+	CheckBound(segment *model.SQLSegment, node ast.StmtNode, binding *parser.Binding, schema *model.SchemaCtx) ([]model.Issue, error)
+}
+
+// DefaultRules returns a fresh instance of every built-in rule with its
+// default settings.
+func DefaultRules() []model.Rule {
+	return []model.Rule{
+		&NoWhereRule{},
+		&SelectStarRule{},
+		&DynamicIdentifierRule{},
+		&IndexMissRule{},
+		&JoinIndexRule{},
+		&NonSargableRule{},
+		&OrderByRule{},
+		&ImplicitConversionRule{},
+		&DeepPaginationRule{Threshold: 5000},
+		&NegativeQueryRule{},
+	}
+}
+
+// intParam reads an integer parameter. ok is false if the key is absent.
+func intParam(params map[string]interface{}, key string) (int64, bool, error) {
+	raw, found := params[key]
+	if !found {
+		return 0, false, nil
+	}
+	switch v := raw.(type) {
+	case int:
+		return int64(v), true, nil
+	case int64:
+		return v, true, nil
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v), true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("parameter %q must be an integer, got %v", key, raw)
+}
+
+// checkParams returns an error for any parameter not in known
+func checkParams(params map[string]interface{}, known ...string) error {
+	for key := range params {
+		found := false
+		for _, k := range known {
+			if key == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown parameter %q (known parameters: %v)", key, known)
+		}
+	}
+	return nil
+}
@@ -11,6 +11,10 @@ type NoWhereRule struct{}
 
 func (r *NoWhereRule) Name() string { return "no_where_clause" }
 
+func (r *NoWhereRule) Description() string { return "UPDATE or DELETE without a WHERE clause writes the whole table" }
+
+func (r *NoWhereRule) DefaultLevel() model.RiskLevel { return model.RiskLevelFatal }
+
 func (r *NoWhereRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
 	var issues []model.Issue
 
@@ -45,6 +49,12 @@ type SelectStarRule struct{}
 
 func (r *SelectStarRule) Name() string { return "select_star" }
 
+func (r *SelectStarRule) Description() string { return "SELECT * fetches columns the caller may not need" }
+
+func (r *SelectStarRule) DefaultLevel() model.RiskLevel { return model.RiskLevelSuggestion }
+
+func (r *SelectStarRule) SizeAware() {}
+
 func (r *SelectStarRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
 	var issues []model.Issue
 
@@ -3,6 +3,7 @@ package auditor
 import (
 	"sql-check/internal/model"
 	"sql-check/internal/parser"
+	"strings"
 	"testing"
 )
 
@@ -111,3 +112,526 @@ func TestSelectStarRule_Check(t *testing.T) {
 		})
 	}
 }
+
+func TestDeepPaginationRule_Configure(t *testing.T) {
+	rule := &DeepPaginationRule{Threshold: 5000}
+
+	if err := rule.Configure(map[string]interface{}{"threshold": 100}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if rule.Threshold != 100 {
+		t.Errorf("Expected threshold 100, got %d", rule.Threshold)
+	}
+
+	if err := rule.Configure(map[string]interface{}{"treshold": 100}); err == nil {
+		t.Errorf("Configure() should reject unknown parameters")
+	}
+	if err := rule.Configure(map[string]interface{}{"threshold": "high"}); err == nil {
+		t.Errorf("Configure() should reject non-integer threshold")
+	}
+}
+
+func TestIndexRules_Joins(t *testing.T) {
+	schema := model.NewSchemaCtx(1)
+	schema.AddTable(&model.Table{
+		Name:    "users",
+		Columns: map[string]*model.Column{"id": {Name: "id", Type: "int"}, "email": {Name: "email", Type: "varchar(255)"}},
+		Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}}, {Name: "idx_email", Columns: []string{"email"}}},
+	})
+	schema.AddTable(&model.Table{
+		Name:    "orders",
+		Columns: map[string]*model.Column{"id": {Name: "id", Type: "bigint"}, "user_id": {Name: "user_id", Type: "int"}, "note": {Name: "note", Type: "text"}},
+		Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}}},
+	})
+
+	tests := []struct {
+		name string
+		sql  string
+		want []string // Issue types
+	}{
+		{
+			name: "filter on the joined table's index",
+			sql:  "SELECT o.id FROM orders o JOIN users u ON u.id = o.user_id WHERE u.email = 'a@b.c'",
+		},
+		{
+			name: "filter on the first table's unindexed column",
+			sql:  "SELECT o.id FROM orders o JOIN users u ON u.id = o.user_id WHERE o.user_id = 1",
+			want: []string{"INDEX_MISS"},
+		},
+		{
+			name: "indexed column only inside a function",
+			sql:  "SELECT id FROM users WHERE LOWER(email) = 'a@b.c'",
+			want: []string{"NON_SARGABLE"},
+		},
+		{
+			name: "unindexed column only inside a function",
+			sql:  "SELECT id FROM orders WHERE UPPER(note) = 'X'",
+			want: []string{"INDEX_MISS"},
+		},
+		{
+			name: "indexed column inside a function and an unindexed column",
+			sql:  "SELECT id FROM users u JOIN orders o ON o.user_id = u.id WHERE LOWER(u.email) = 'a' AND o.note = 'x'",
+			want: []string{"INDEX_MISS", "NON_SARGABLE"},
+		},
+		{
+			name: "implicit conversion on an aliased column",
+			sql:  "SELECT o.id FROM orders o JOIN users u ON u.id = o.user_id WHERE u.email = 1",
+			want: []string{"IMPLICIT_CONVERSION"},
+		},
+	}
+
+	p := parser.NewSQLParser()
+	rules := []model.Rule{&IndexMissRule{}, &ImplicitConversionRule{}, &NonSargableRule{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := p.Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			var got []string
+			for _, rule := range rules {
+				issues, err := rule.Check(&model.SQLSegment{SQL: tt.sql}, stmt, schema)
+				if err != nil {
+					t.Fatalf("Check failed: %v", err)
+				}
+				for _, issue := range issues {
+					got = append(got, issue.Type)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinIndexRule_Check(t *testing.T) {
+	schema := model.NewSchemaCtx(1)
+	schema.AddTable(&model.Table{
+		Name:    "users",
+		Columns: map[string]*model.Column{"id": {Name: "id", Type: "int"}, "email": {Name: "email", Type: "varchar(255)"}},
+		Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}}},
+	})
+	schema.AddTable(&model.Table{
+		Name: "orders",
+		Columns: map[string]*model.Column{
+			"id": {Name: "id", Type: "bigint"}, "user_id": {Name: "user_id", Type: "int"},
+			"coupon": {Name: "coupon", Type: "varchar(16)"}, "status": {Name: "status", Type: "varchar(16)"},
+		},
+		Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}}, {Name: "idx_status", Columns: []string{"status"}}},
+	})
+	schema.AddTable(&model.Table{
+		Name:    "coupons",
+		Columns: map[string]*model.Column{"code": {Name: "code", Type: "varchar(16)"}},
+	})
+
+	tests := []struct {
+		name string
+		sql  string
+		want []string // Tables reported
+	}{
+		{
+			name: "inner join, one side indexed",
+			sql:  "SELECT * FROM orders o JOIN users u ON u.id = o.user_id",
+		},
+		{
+			name: "left join on an unindexed column of the right table",
+			sql:  "SELECT * FROM users u LEFT JOIN orders o ON o.user_id = u.id",
+			want: []string{"orders"},
+		},
+		{
+			name: "left join where the right table is filtered by an index",
+			sql:  "SELECT * FROM users u LEFT JOIN orders o ON o.user_id = u.id AND o.status = 'paid'",
+		},
+		{
+			name: "right join",
+			sql:  "SELECT * FROM orders o RIGHT JOIN users u ON o.user_id = u.id",
+			want: []string{"orders"},
+		},
+		{
+			name: "inner join with neither side indexed",
+			sql:  "SELECT * FROM orders o JOIN coupons c ON c.code = o.coupon",
+			want: []string{"coupons"},
+		},
+		{
+			name: "join in WHERE",
+			sql:  "SELECT * FROM orders o, coupons c WHERE c.code = o.coupon",
+			want: []string{"coupons"},
+		},
+		{
+			name: "USING",
+			sql:  "SELECT * FROM users LEFT JOIN orders USING (id)",
+		},
+		{
+			name: "join in a subquery",
+			sql:  "SELECT * FROM users WHERE id IN (SELECT o.user_id FROM orders o STRAIGHT_JOIN coupons c ON c.code = o.coupon)",
+			want: []string{"coupons"},
+		},
+		{
+			name: "cross join",
+			sql:  "SELECT * FROM orders o JOIN coupons c",
+		},
+	}
+
+	p := parser.NewSQLParser()
+	rule := &JoinIndexRule{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := p.Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			issues, err := rule.Check(&model.SQLSegment{SQL: tt.sql}, stmt, schema)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			var got []string
+			for _, issue := range issues {
+				if issue.Type != "JOIN_INDEX_MISS" {
+					t.Errorf("Unexpected issue type %s", issue.Type)
+				}
+				got = append(got, strings.Split(issue.Message, "'")[1])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Check() reported %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexMissRule_PrefixDepth(t *testing.T) {
+	schema := model.NewSchemaCtx(1)
+	schema.AddTable(&model.Table{
+		Name: "orders",
+		Columns: map[string]*model.Column{
+			"id": {Name: "id", Type: "bigint"}, "user_id": {Name: "user_id", Type: "int"},
+			"status": {Name: "status", Type: "varchar(16)"}, "created_at": {Name: "created_at", Type: "datetime"},
+		},
+		Indexes: []*model.Index{
+			{Name: "PRIMARY", Columns: []string{"id"}, Unique: true},
+			{Name: "idx_user_status_created", Columns: []string{"user_id", "status", "created_at"}},
+		},
+	})
+
+	tests := []struct {
+		name string
+		sql  string
+		want string // Message prefix of the PARTIAL_INDEX_USE issue, empty for none
+	}{
+		{
+			name: "equality on every column",
+			sql:  "SELECT id FROM orders WHERE user_id = ? AND status IN ('a', 'b') AND created_at > ?",
+		},
+		{
+			name: "range stops the prefix",
+			sql:  "SELECT id FROM orders WHERE user_id > ? AND status = 'paid'",
+			want: "Index idx_user_status_created(user_id, status, created_at) on 'orders' is used for 1 of the 2 columns the query filters on (effective key: user_id): the range condition on user_id",
+		},
+		{
+			name: "gap in the prefix",
+			sql:  "SELECT id FROM orders WHERE user_id = ? AND created_at BETWEEN ? AND ?",
+			want: "Index idx_user_status_created(user_id, status, created_at) on 'orders' is used for 1 of the 2 columns the query filters on (effective key: user_id): status has no condition",
+		},
+		{
+			name: "conditions under OR do not count",
+			sql:  "SELECT id FROM orders WHERE user_id = ? AND (status = 'a' OR created_at > ?)",
+		},
+		{
+			name: "several ranges",
+			sql:  "SELECT id FROM orders WHERE user_id > ? AND status > 'a'",
+		},
+		{
+			name: "gap in the prefix, but a unique lookup",
+			sql:  "SELECT id FROM orders WHERE id = ? AND user_id = ? AND created_at = ?",
+		},
+	}
+
+	p := parser.NewSQLParser()
+	rule := &IndexMissRule{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := p.Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			issues, err := rule.Check(&model.SQLSegment{SQL: tt.sql}, stmt, schema)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			var got []model.Issue
+			for _, issue := range issues {
+				if issue.Type == "PARTIAL_INDEX_USE" {
+					got = append(got, issue)
+				}
+			}
+			if tt.want == "" {
+				if len(got) != 0 {
+					t.Errorf("Unexpected issues %v", got)
+				}
+				return
+			}
+			if len(got) != 1 || !strings.HasPrefix(got[0].Message, tt.want) {
+				t.Errorf("Check() = %v, want message %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNonSargableRule_Check(t *testing.T) {
+	schema := model.NewSchemaCtx(1)
+	schema.AddTable(&model.Table{
+		Name: "orders",
+		Columns: map[string]*model.Column{
+			"id": {Name: "id", Type: "bigint"}, "user_id": {Name: "user_id", Type: "int"},
+			"created_at": {Name: "created_at", Type: "datetime"}, "note": {Name: "note", Type: "text"},
+			"status":   {Name: "status", Type: "varchar(16)"},
+			"code":     {Name: "code", Type: "varchar(16)"},
+			"code_low": {Name: "code_low", Type: "varchar(16)", Generated: "LOWER(`code`)"},
+		},
+		Indexes: []*model.Index{
+			{Name: "PRIMARY", Columns: []string{"id"}},
+			{Name: "idx_user_status", Columns: []string{"user_id", "status"}},
+			{Name: "idx_created", Columns: []string{"created_at"}},
+			{Name: "idx_day", Columns: []string{"(DATE(`created_at`))"}},
+			{Name: "idx_code_low", Columns: []string{"code_low"}},
+		},
+	})
+	schema.AddTable(&model.Table{
+		Name:    "users",
+		Columns: map[string]*model.Column{"id": {Name: "id", Type: "int"}, "email": {Name: "email", Type: "varchar(255)"}},
+		Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}}, {Name: "idx_email", Columns: []string{"email"}}},
+	})
+	schema.AddTable(&model.Table{
+		Name:    "events",
+		Columns: map[string]*model.Column{"id": {Name: "id", Type: "bigint"}, "created_at": {Name: "created_at", Type: "datetime"}},
+		Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}}, {Name: "idx_created", Columns: []string{"created_at"}}},
+	})
+
+	tests := []struct {
+		name string
+		sql  string
+		want string // Wrapper in the message, empty for no issue
+		hint string // Part of the suggestion
+	}{
+		{name: "function", sql: "SELECT id FROM users WHERE LOWER(email) = 'a@b.c'", want: "LOWER()"},
+		{name: "arithmetic", sql: "SELECT id FROM users WHERE id + 1 = 10", want: "arithmetic", hint: ": id = 10 - 1."},
+		{name: "arithmetic on the value side", sql: "SELECT id FROM users WHERE ? < 2 + id", want: "arithmetic", hint: ": id > ? - 2."},
+		{name: "value side", sql: "SELECT id FROM users WHERE 10 = id * 2", want: "arithmetic", hint: "arithmetic in id * 2 "},
+		{name: "cast", sql: "SELECT id FROM users WHERE CAST(id AS CHAR) = '1'", want: "CAST()"},
+		{name: "collation", sql: "SELECT id FROM users WHERE email COLLATE utf8mb4_bin = 'A'", want: "COLLATE utf8mb4_bin"},
+		{name: "join condition", sql: "SELECT o.id FROM orders o JOIN users u ON u.id + 0 = o.user_id", want: "arithmetic"},
+		{name: "after equality columns", sql: "SELECT id FROM orders WHERE user_id = 1 AND TRIM(status) = 'paid'", want: "TRIM()"},
+		{name: "not the leading column", sql: "SELECT id FROM orders WHERE TRIM(status) = 'paid'"},
+		{name: "functional index", sql: "SELECT o.id FROM orders o WHERE date(o.created_at) = '2024-01-01'"},
+		{name: "other function than the functional index", sql: "SELECT id FROM orders WHERE YEAR(created_at) = 2024", want: "YEAR()",
+			hint: "created_at >= '2024-01-01' AND created_at < '2025-01-01'"},
+		{name: "day", sql: "SELECT id FROM events WHERE DATE(created_at) = ?", want: "DATE()",
+			hint: "created_at >= ? AND created_at < ? + INTERVAL 1 DAY"},
+		{name: "days before", sql: "SELECT id FROM events WHERE DATE(created_at) <= '2024-03-05'", want: "DATE()",
+			hint: "created_at < '2024-03-05' + INTERVAL 1 DAY,"},
+		{name: "year parameter", sql: "SELECT id FROM events WHERE YEAR(created_at) > ?", want: "YEAR()",
+			hint: "created_at >= MAKEDATE(? + 1, 1),"},
+		{name: "month", sql: "SELECT id FROM events WHERE MONTH(created_at) = 3", want: "MONTH()", hint: "filter on the year as well"},
+		{name: "date format", sql: "SELECT id FROM events WHERE DATE_FORMAT(created_at, '%Y-%m') = '2024-03'", want: "DATE_FORMAT()",
+			hint: "formats as '2024-03'"},
+		{name: "unix timestamp", sql: "SELECT id FROM events WHERE UNIX_TIMESTAMP(created_at) >= ?", want: "UNIX_TIMESTAMP()",
+			hint: "created_at >= FROM_UNIXTIME(?)"},
+		{name: "indexed generated column", sql: "SELECT id FROM orders WHERE lower(code) = 'x'"},
+		{name: "not indexed", sql: "SELECT id FROM orders WHERE UPPER(note) = 'X'"},
+		{name: "value is a function", sql: "SELECT id FROM orders WHERE created_at > NOW() - INTERVAL 1 DAY"},
+		{name: "under OR", sql: "SELECT id FROM users WHERE LOWER(email) = 'a' OR id = 1"},
+	}
+
+	p := parser.NewSQLParser()
+	rule := &NonSargableRule{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := p.Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			issues, err := rule.Check(&model.SQLSegment{SQL: tt.sql}, stmt, schema)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if tt.want == "" {
+				if len(issues) != 0 {
+					t.Errorf("Unexpected issues %v", issues)
+				}
+				return
+			}
+			if len(issues) != 1 || issues[0].Type != "NON_SARGABLE" || !strings.Contains(issues[0].Message, "inside "+tt.want+",") {
+				t.Fatalf("Check() = %v, want one NON_SARGABLE issue on %s", issues, tt.want)
+			}
+			if !strings.Contains(issues[0].Suggestion, tt.hint) {
+				t.Errorf("Suggestion = %q, want it to contain %q", issues[0].Suggestion, tt.hint)
+			}
+		})
+	}
+
+	// A functional index is an index hit for IndexMissRule
+	sql := "SELECT id FROM orders WHERE DATE(created_at) = '2024-01-01'"
+	stmt, _ := p.Parse(sql)
+	issues, _ := (&IndexMissRule{}).Check(&model.SQLSegment{SQL: sql}, stmt, schema)
+	if len(issues) != 0 {
+		t.Errorf("IndexMissRule.Check() = %v, want none", issues)
+	}
+}
+
+func TestOrderByRule_Check(t *testing.T) {
+	schema := model.NewSchemaCtx(1)
+	schema.AddTable(&model.Table{
+		Name: "orders",
+		Columns: map[string]*model.Column{
+			"id": {Name: "id", Type: "bigint"}, "user_id": {Name: "user_id", Type: "int"},
+			"status": {Name: "status", Type: "varchar(16)"}, "created_at": {Name: "created_at", Type: "datetime"},
+			"amount": {Name: "amount", Type: "decimal(10,2)"},
+		},
+		Indexes: []*model.Index{
+			{Name: "PRIMARY", Columns: []string{"id"}, Unique: true},
+			{Name: "idx_user_created", Columns: []string{"user_id", "created_at"}},
+			{Name: "idx_status_amount", Columns: []string{"status", "amount"}, Descending: []bool{false, true}},
+		},
+	})
+	schema.AddTable(&model.Table{
+		Name:    "users",
+		Columns: map[string]*model.Column{"id": {Name: "id", Type: "int"}, "name": {Name: "name", Type: "varchar(64)"}},
+		Indexes: []*model.Index{{Name: "PRIMARY", Columns: []string{"id"}, Unique: true}},
+	})
+
+	tests := []struct {
+		name   string
+		sql    string
+		want   []string // "TYPE LEVEL"
+		reason string   // Part of the message
+	}{
+		{name: "equality prefix then order", sql: "SELECT id FROM orders WHERE user_id = ? ORDER BY created_at DESC LIMIT 20"},
+		{name: "index scan in order", sql: "SELECT id FROM orders ORDER BY user_id, created_at LIMIT 20"},
+		{name: "backward scan", sql: "SELECT id FROM orders ORDER BY user_id DESC, created_at DESC"},
+		{name: "descending key part", sql: "SELECT id FROM orders WHERE status = 'paid' ORDER BY amount DESC LIMIT 10"},
+		{name: "constant columns are skipped", sql: "SELECT id FROM orders WHERE user_id = ? ORDER BY user_id, created_at"},
+		{name: "single row", sql: "SELECT id FROM orders WHERE id = 1 ORDER BY created_at"},
+		{name: "primary key after a secondary index", sql: "SELECT id FROM orders WHERE user_id = ? AND created_at = ? ORDER BY id DESC LIMIT 20"},
+		{name: "ORDER BY NULL", sql: "SELECT status, COUNT(*) FROM orders GROUP BY status ORDER BY NULL"},
+		{name: "unindexed order with LIMIT", sql: "SELECT id FROM orders ORDER BY created_at LIMIT 20", want: []string{"FILESORT WARNING"}},
+		{name: "unindexed order", sql: "SELECT id FROM orders WHERE user_id = ? ORDER BY amount", want: []string{"FILESORT SUGGESTION"}},
+		{name: "range before the order column", sql: "SELECT id FROM orders WHERE user_id > ? ORDER BY created_at LIMIT 5", want: []string{"FILESORT WARNING"},
+			reason: "'orders' is read through index idx_user_created(user_id, created_at), looked up by user_id > ?,"},
+		{name: "joined table looked up by the join", sql: "SELECT u.id FROM users u JOIN orders o ON o.user_id = u.id ORDER BY o.created_at", want: []string{"FILESORT SUGGESTION"},
+			reason: "looked up by the join condition o.user_id = u.id,"},
+		{name: "IN list before the order column", sql: "SELECT id FROM orders WHERE user_id IN (1, 2) ORDER BY created_at LIMIT 5", want: []string{"FILESORT WARNING"}},
+		{name: "mixed directions", sql: "SELECT id FROM orders ORDER BY user_id, created_at DESC LIMIT 5", want: []string{"FILESORT WARNING"}},
+		{name: "expression", sql: "SELECT id FROM orders ORDER BY RAND() LIMIT 1", want: []string{"FILESORT WARNING"}},
+		{name: "several tables", sql: "SELECT o.id FROM orders o JOIN users u ON u.id = o.user_id ORDER BY u.name, o.id", want: []string{"FILESORT SUGGESTION"}},
+		{name: "right table of a left join", sql: "SELECT u.id FROM orders o LEFT JOIN users u ON u.id = o.user_id ORDER BY u.id LIMIT 5", want: []string{"FILESORT WARNING"}},
+		{name: "group by index prefix", sql: "SELECT user_id, COUNT(*) FROM orders GROUP BY user_id ORDER BY user_id"},
+		{name: "group by unindexed column", sql: "SELECT amount, COUNT(*) FROM orders GROUP BY amount", want: []string{"TEMPORARY_TABLE SUGGESTION"}},
+		{name: "order differs from group", sql: "SELECT user_id, COUNT(*) c FROM orders GROUP BY user_id ORDER BY COUNT(*) DESC LIMIT 10", want: []string{"FILESORT WARNING"}},
+		{name: "select list alias", sql: "SELECT user_id, created_at AS day FROM orders ORDER BY day"},
+	}
+
+	p := parser.NewSQLParser()
+	rule := &OrderByRule{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := p.Parse(tt.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			issues, err := rule.Check(&model.SQLSegment{SQL: tt.sql}, stmt, schema)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.Type+" "+string(issue.Level))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Check() = %v, want %v", issues, tt.want)
+			}
+			if tt.reason != "" && (len(issues) == 0 || !strings.Contains(issues[0].Message, tt.reason)) {
+				t.Errorf("Message = %v, want it to contain %q", issues, tt.reason)
+			}
+		})
+	}
+}
+
+func TestDeepPaginationRule_Check(t *testing.T) {
+	rule := &DeepPaginationRule{Threshold: 5000}
+	p := parser.NewSQLParser()
+	for sql, want := range map[string]int{
+		"SELECT id FROM users LIMIT 10000, 10":        1,
+		"SELECT id FROM users LIMIT 10 OFFSET 100000": 1,
+		"SELECT id FROM users LIMIT 100, 10":          0,
+	} {
+		stmt, err := p.Parse(sql)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", sql, err)
+		}
+		issues, err := rule.Check(&model.SQLSegment{SQL: sql}, stmt, nil)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if len(issues) != want {
+			t.Errorf("Check(%q) = %v, want %d issues", sql, issues, want)
+		}
+	}
+}
+
+func TestDynamicIdentifierRule_Check(t *testing.T) {
+	p := parser.NewSQLParser()
+	rule := &DynamicIdentifierRule{}
+
+	// Expanded from "SELECT id FROM %s WHERE name = '%s'; DELETE FROM `%s` WHERE id = %d"
+	seg := &model.SQLSegment{
+		SQL:       "SELECT id FROM tpl_ident WHERE name = 'tpl'; DELETE FROM `tpl_ident` WHERE id = 1",
+		Templated: true,
+		TemplateParams: []model.TemplateParam{
+			{Verb: "%s", Kind: model.TemplateIdentifier, Offset: 15},
+			{Verb: "%s", Kind: model.TemplateString, Offset: 39},
+			{Verb: "%s", Kind: model.TemplateIdentifier, Offset: 58},
+			{Verb: "%d", Kind: model.TemplateNumber, Offset: 80},
+		},
+	}
+	stmts, err := p.ParseAll(seg.SQL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	for i, want := range []int{15, 58} {
+		issues, err := rule.CheckBound(seg, stmts[i].Node, parser.BindStatement(stmts[i], nil), nil)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if len(issues) != 1 || issues[0].Offset != want {
+			t.Errorf("statement %d: got %+v, want one issue at offset %d", i, issues, want)
+		}
+	}
+
+	// Identical statements each get their own parameter
+	// Expanded from "DELETE FROM %s WHERE id = 1; DELETE FROM %s WHERE id = 1"
+	twice := &model.SQLSegment{
+		SQL:       "DELETE FROM tpl_ident WHERE id = 1; DELETE FROM tpl_ident WHERE id = 1",
+		Templated: true,
+		TemplateParams: []model.TemplateParam{
+			{Verb: "%s", Kind: model.TemplateIdentifier, Offset: 12},
+			{Verb: "%s", Kind: model.TemplateIdentifier, Offset: 48},
+		},
+	}
+	stmts, err = p.ParseAll(twice.SQL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	for i, want := range []int{12, 48} {
+		issues, _ := rule.CheckBound(twice, stmts[i].Node, parser.BindStatement(stmts[i], nil), nil)
+		if len(issues) != 1 || issues[0].Offset != want {
+			t.Errorf("identical statement %d: got %+v, want one issue at offset %d", i, issues, want)
+		}
+	}
+
+	plain := &model.SQLSegment{SQL: "SELECT id FROM users"}
+	stmt, _ := p.Parse(plain.SQL)
+	if issues, _ := rule.Check(plain, stmt, nil); len(issues) != 0 {
+		t.Errorf("Plain SQL: got %d issues, want 0", len(issues))
+	}
+}
@@ -0,0 +1,449 @@
+package auditor
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sql-check/internal/model"
+	"sql-check/internal/parser"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/parser/test_driver"
+)
+
+// NonSargableRule detects conditions that wrap an indexed column in a
+// function, cast, arithmetic or collation change, which keeps the index on
+// the column from being used (the condition is not "sargable")
+type NonSargableRule struct{}
+
+func (r *NonSargableRule) Name() string { return "non_sargable" }
+
+func (r *NonSargableRule) Description() string {
+	return "Indexed column wrapped in a function, cast, arithmetic or COLLATE cannot use its index"
+}
+
+func (r *NonSargableRule) DefaultLevel() model.RiskLevel { return model.RiskLevelWarning }
+
+func (r *NonSargableRule) SizeAware() {}
+
+func (r *NonSargableRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
+	return r.CheckBound(seg, node, parser.Bind(node, schema), schema)
+}
+
+// CheckBound looks at the WHERE and ON conditions of every query block. A
+// wrapped column is reported if an index could otherwise look it up (it is
+// the leading column, or follows columns compared by equality) and no
+// functional index or indexed generated column has the same expression.
+func (r *NonSargableRule) CheckBound(seg *model.SQLSegment, node ast.StmtNode, binding *parser.Binding, schema *model.SchemaCtx) ([]model.Issue, error) {
+	var issues []model.Issue
+
+	for _, block := range queryBlocks(node) {
+		from, where := blockClauses(block)
+		scope := binding.Scope(block)
+		if scope == nil {
+			continue
+		}
+		conds := []ast.ExprNode{where}
+		if from != nil {
+			conds = append(conds, onConditions(from.TableRefs)...)
+		}
+
+		var preds []predicate
+		var wrapped []wrappedColumn
+		for _, cond := range conds {
+			preds = append(preds, predicates(binding, cond)...)
+			wrapped = append(wrapped, wrappedColumns(binding, cond)...)
+		}
+
+		for _, w := range wrapped {
+			table := w.col.Ref.Table
+			if table == nil || !slices.Contains(scope.Tables, w.col.Ref) {
+				continue // Not a base table, or an outer column of a subquery
+			}
+			expr := parser.NormalizeExpr(w.wrapper)
+			indexed, covered := indexedExpression(table, w.col.Name, expr, lookupColumns(w.col.Ref, preds))
+			if !indexed || covered {
+				continue
+			}
+			issues = append(issues, model.Issue{
+				Type:  "NON_SARGABLE",
+				Level: model.RiskLevelWarning,
+				Message: fmt.Sprintf("Indexed column '%s.%s' is used inside %s, so its index cannot be used.",
+					table.Name, w.col.Name, describeWrapper(w.wrapper)),
+				Suggestion: sargableRewrite(binding, table.Name, expr, w),
+				Segment:    *seg,
+				Offset:     w.expr.OriginTextPosition(),
+				Table:      table,
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// wrappedColumn is a condition on an expression of a single column, such
+// as DATE(created_at) = ?
+type wrappedColumn struct {
+	col     *parser.BoundColumn
+	wrapper ast.ExprNode // The expression around the column
+	expr    ast.ExprNode // The whole condition
+	// op compares the wrapper, on the left, with value; zero for IN,
+	// BETWEEN, LIKE and IS NULL
+	op    opcode.Op
+	value ast.ExprNode
+}
+
+// wrappedColumns returns the terms of the top-level AND of a condition that
+// compare an expression of one column with values not depending on its table
+func wrappedColumns(binding *parser.Binding, cond ast.ExprNode) []wrappedColumn {
+	var wrapped []wrappedColumn
+	for _, term := range conjuncts(cond) {
+		var sides []wrappedColumn // Wrapper, comparison and value of each side
+		switch e := term.(type) {
+		case *ast.BinaryOperationExpr:
+			switch e.Op {
+			case opcode.EQ, opcode.NullEQ, opcode.LT, opcode.LE, opcode.GT, opcode.GE:
+				sides = []wrappedColumn{{wrapper: e.L, op: e.Op, value: e.R}, {wrapper: e.R, op: mirrorOp(e.Op), value: e.L}}
+			}
+		case *ast.PatternInExpr:
+			if !e.Not {
+				sides = []wrappedColumn{{wrapper: e.Expr}}
+			}
+		case *ast.BetweenExpr:
+			if !e.Not {
+				sides = []wrappedColumn{{wrapper: e.Expr}}
+			}
+		case *ast.PatternLikeOrIlikeExpr:
+			if !e.Not {
+				sides = []wrappedColumn{{wrapper: e.Expr, value: e.Pattern}}
+			}
+		case *ast.IsNullExpr:
+			if !e.Not {
+				sides = []wrappedColumn{{wrapper: e.Expr}}
+			}
+		}
+
+		for _, side := range sides {
+			if w, ok := wrappedColumnOf(binding, side.wrapper, side.value); ok {
+				w.expr, w.op, w.value = term, side.op, side.value
+				wrapped = append(wrapped, w)
+			}
+		}
+	}
+	return wrapped
+}
+
+// mirrorOp is the comparison with its sides swapped
+func mirrorOp(op opcode.Op) opcode.Op {
+	switch op {
+	case opcode.LT:
+		return opcode.GT
+	case opcode.LE:
+		return opcode.GE
+	case opcode.GT:
+		return opcode.LT
+	case opcode.GE:
+		return opcode.LE
+	}
+	return op
+}
+
+func wrappedColumnOf(binding *parser.Binding, expr, value ast.ExprNode) (wrappedColumn, bool) {
+	if boundColumn(binding, expr) != nil {
+		return wrappedColumn{}, false // Bare column
+	}
+	var cols []*parser.BoundColumn
+	expr.Accept(&columnFinder{fn: func(c *ast.ColumnNameExpr) {
+		cols = append(cols, binding.Column(c))
+	}})
+	if len(cols) != 1 || cols[0] == nil {
+		return wrappedColumn{}, false
+	}
+	col := cols[0]
+
+	dependent := false
+	if value != nil {
+		value.Accept(&columnFinder{fn: func(c *ast.ColumnNameExpr) {
+			if bc := binding.Column(c); bc != nil && bc.Ref == col.Ref {
+				dependent = true
+			}
+		}})
+	}
+	if dependent {
+		return wrappedColumn{}, false
+	}
+	return wrappedColumn{col: col, wrapper: expr}, true
+}
+
+// indexedExpression tells whether an index could look up column if it
+// stood alone, given the columns compared with equality (from
+// lookupColumns), and whether an index covers expr, the normalized
+// expression around it: a functional index key part or an indexed generated
+// column with that expression
+func indexedExpression(table *model.Table, column, expr string, cols map[string]predicate) (indexed, covered bool) {
+	generated := make(map[string]bool)
+	for name, col := range table.Columns {
+		if col.Generated != "" && normalizedText(col.Generated) == expr {
+			generated[strings.ToLower(name)] = true
+		}
+	}
+
+	for _, idx := range table.Indexes {
+		for _, part := range idx.Columns {
+			if model.IsIndexExpression(part) {
+				if normalizedText(part) == expr {
+					covered = true
+				}
+				break // Expression parts are not looked up by other conditions
+			}
+			name := strings.ToLower(part)
+			if generated[name] {
+				covered = true
+			}
+			if strings.EqualFold(part, column) {
+				indexed = true
+			}
+			if p, ok := cols[name]; !ok || p.kind != predEqual {
+				break
+			}
+		}
+	}
+	return indexed, covered
+}
+
+// normalizedText is parser.NormalizeExpr of an expression given as text,
+// empty if it does not parse
+func normalizedText(expr string) string {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return ""
+	}
+	return parser.NormalizeExpr(node)
+}
+
+// onConditions returns the ON conditions of the joins of a FROM clause
+func onConditions(node ast.ResultSetNode) []ast.ExprNode {
+	switch n := node.(type) {
+	case *ast.TableSource:
+		if join, ok := n.Source.(*ast.Join); ok {
+			return onConditions(join)
+		}
+	case *ast.Join:
+		var conds []ast.ExprNode
+		if n.Left != nil {
+			conds = append(conds, onConditions(n.Left)...)
+		}
+		if n.Right != nil {
+			conds = append(conds, onConditions(n.Right)...)
+		}
+		if n.On != nil {
+			conds = append(conds, n.On.Expr)
+		}
+		return conds
+	}
+	return nil
+}
+
+// describeWrapper names what is done to the column, for messages
+func describeWrapper(wrapper ast.ExprNode) string {
+	switch e := wrapper.(type) {
+	case *ast.ParenthesesExpr:
+		return describeWrapper(e.Expr)
+	case *ast.FuncCallExpr:
+		return strings.ToUpper(e.FnName.O) + "()"
+	case *ast.FuncCastExpr:
+		return "CAST()"
+	case *ast.SetCollationExpr:
+		return "COLLATE " + e.Collate
+	case *ast.BinaryOperationExpr, *ast.UnaryOperationExpr:
+		return "arithmetic"
+	}
+	return "an expression"
+}
+
+// sargableRewrite suggests how to keep the column of w bare, in terms of the
+// value it is compared with where the rewrite depends on it
+func sargableRewrite(binding *parser.Binding, table, expr string, w wrappedColumn) string {
+	column := w.col.Name
+	functional := fmt.Sprintf("add a functional index on %s((%s))", table, expr)
+	value := "?"
+	if w.value != nil {
+		value = exprText(binding, w.value)
+	}
+
+	wrapper := w.wrapper
+	for {
+		paren, ok := wrapper.(*ast.ParenthesesExpr)
+		if !ok {
+			break
+		}
+		wrapper = paren.Expr
+	}
+
+	switch e := wrapper.(type) {
+	case *ast.FuncCallExpr:
+		switch e.FnName.L {
+		case "date":
+			if w.op != 0 {
+				return fmt.Sprintf("Compare the bare column with the bounds of the day instead: %s, or %s.",
+					periodRange(column, w.op, value, value+" + INTERVAL 1 DAY"), functional)
+			}
+		case "to_days":
+			if w.op != 0 {
+				return fmt.Sprintf("Compare the bare column with the bounds of the day instead: %s, or %s.",
+					periodRange(column, w.op, "FROM_DAYS("+value+")", "FROM_DAYS("+value+" + 1)"), functional)
+			}
+		case "year":
+			if w.op != 0 {
+				start, next := "MAKEDATE("+value+", 1)", "MAKEDATE("+value+" + 1, 1)"
+				if year, err := strconv.Atoi(value); err == nil {
+					start, next = fmt.Sprintf("'%d-01-01'", year), fmt.Sprintf("'%d-01-01'", year+1)
+				}
+				return fmt.Sprintf("Compare the bare column with the bounds of the year instead: %s, or %s.",
+					periodRange(column, w.op, start, next), functional)
+			}
+		case "month":
+			return fmt.Sprintf("MONTH() matches that month of every year, which is not one range of %s: filter on the year as well and compare %s with the bounds of the month, or %s.",
+				column, column, functional)
+		case "date_format":
+			return fmt.Sprintf("DATE_FORMAT() compares text: compare %s with the bounds of the period that formats as %s instead (its first day, and the first day of the next one), or %s.",
+				column, value, functional)
+		case "unix_timestamp":
+			if w.op != 0 {
+				return fmt.Sprintf("Convert the value instead of the column: %s %s FROM_UNIXTIME(%s), or %s.",
+					column, opText(w.op), value, functional)
+			}
+			return fmt.Sprintf("Convert the values with FROM_UNIXTIME() and compare %s with them instead, or %s.", column, functional)
+		case "lower", "upper", "lcase", "ucase":
+			return fmt.Sprintf("Case-insensitive collations (the default) already ignore case: compare %s directly, or %s.", column, functional)
+		case "trim", "ltrim", "rtrim":
+			return fmt.Sprintf("Store %s trimmed and compare it directly, or %s.", column, functional)
+		case "substring", "substr", "left", "mid":
+			return fmt.Sprintf("Match the prefix with %s LIKE 'prefix%%' instead, or %s.", column, functional)
+		case "ifnull", "coalesce":
+			return fmt.Sprintf("Spell out the NULL case instead: (%s = ? OR %s IS NULL).", column, column)
+		}
+	case *ast.FuncCastExpr:
+		return fmt.Sprintf("Compare %s with a value of its own type instead of converting the column.", column)
+	case *ast.SetCollationExpr:
+		return fmt.Sprintf("Compare %s in its own collation, or change the collation of the column.", column)
+	case *ast.BinaryOperationExpr, *ast.UnaryOperationExpr:
+		if moved, ok := movedArithmetic(binding, wrapper, w); ok {
+			return fmt.Sprintf("Move the arithmetic to the other side of the comparison so that %s stands alone: %s.", column, moved)
+		}
+		return fmt.Sprintf("Move the arithmetic in %s to the other side of the comparison so that %s stands alone.", exprText(binding, wrapper), column)
+	}
+	return fmt.Sprintf("Rewrite the condition so that %s stands alone, or %s.", column, functional)
+}
+
+// periodRange is the condition on column for comparing the period it falls
+// in, which runs from start up to next, with op
+func periodRange(column string, op opcode.Op, start, next string) string {
+	switch op {
+	case opcode.LT:
+		return fmt.Sprintf("%s < %s", column, start)
+	case opcode.LE:
+		return fmt.Sprintf("%s < %s", column, next)
+	case opcode.GT:
+		return fmt.Sprintf("%s >= %s", column, next)
+	case opcode.GE:
+		return fmt.Sprintf("%s >= %s", column, start)
+	}
+	return fmt.Sprintf("%s >= %s AND %s < %s", column, start, column, next)
+}
+
+// movedArithmetic rewrites a comparison of column + k or column - k as one
+// of the bare column, false for other arithmetic
+func movedArithmetic(binding *parser.Binding, wrapper ast.ExprNode, w wrappedColumn) (string, bool) {
+	e, ok := wrapper.(*ast.BinaryOperationExpr)
+	if !ok || w.op == 0 {
+		return "", false
+	}
+	var inverse opcode.Op
+	switch e.Op {
+	case opcode.Plus:
+		inverse = opcode.Minus
+	case opcode.Minus:
+		inverse = opcode.Plus
+	default:
+		return "", false // Multiplying by a negative number reverses the comparison
+	}
+
+	col, k := e.L, e.R
+	if _, ok := col.(*ast.ColumnNameExpr); !ok {
+		if e.Op == opcode.Minus {
+			return "", false // k - column reverses the comparison
+		}
+		col, k = e.R, e.L
+	}
+	if _, ok := col.(*ast.ColumnNameExpr); !ok {
+		return "", false
+	}
+	moved := &ast.BinaryOperationExpr{Op: w.op, L: col, R: &ast.BinaryOperationExpr{Op: inverse, L: w.value, R: k}}
+	return exprText(binding, moved), true
+}
+
+// exprText is the SQL text of an expression, for messages and suggestions,
+// with bind parameters as written in the SQL
+func exprText(binding *parser.Binding, expr ast.ExprNode) string {
+	var sb strings.Builder
+	flags := format.RestoreStringSingleQuotes | format.RestoreKeyWordUppercase | format.RestoreStringWithoutCharset |
+		format.RestoreSpacesAroundBinaryOperation
+	if err := expr.Restore(format.NewRestoreCtx(flags, &sb)); err != nil {
+		return "?"
+	}
+	text := sb.String()
+
+	// Markers are restored as '?', in the order they are visited
+	var names []string
+	expr.Accept(&markerFinder{fn: func(marker *test_driver.ParamMarkerExpr) {
+		names = append(names, binding.Placeholder(marker))
+	}})
+	if len(names) == 0 {
+		return text
+	}
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := min(parser.SkipQuoted(text, i), len(text)-1)
+			out.WriteString(text[i : end+1])
+			i = end
+		case c == '?' && len(names) > 0:
+			out.WriteString(names[0])
+			names = names[1:]
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// markerFinder calls fn for each '?' marker
+type markerFinder struct {
+	fn func(marker *test_driver.ParamMarkerExpr)
+}
+
+func (v *markerFinder) Enter(in ast.Node) (ast.Node, bool) {
+	if marker, ok := in.(*test_driver.ParamMarkerExpr); ok {
+		v.fn(marker)
+	}
+	return in, false
+}
+
+func (v *markerFinder) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// opText is the SQL text of a comparison operator
+func opText(op opcode.Op) string {
+	var sb strings.Builder
+	if err := op.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return "="
+	}
+	return sb.String()
+}
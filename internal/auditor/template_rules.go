@@ -0,0 +1,55 @@
+package auditor
+
+import (
+	"fmt"
+
+	"sql-check/internal/model"
+	"sql-check/internal/parser"
+
+	"github.com/pingcap/tidb/parser/ast"
+)
+
+// DynamicIdentifierRule detects printf-style templates that interpolate a
+// table or column name (an unquoted or backquoted %s). Such names cannot be
+// bind parameters, so whatever the argument holds becomes part of the SQL.
+type DynamicIdentifierRule struct{}
+
+func (r *DynamicIdentifierRule) Name() string { return "dynamic_identifier" }
+
+func (r *DynamicIdentifierRule) Description() string {
+	return "Table or column name interpolated into a query template"
+}
+
+func (r *DynamicIdentifierRule) DefaultLevel() model.RiskLevel { return model.RiskLevelWarning }
+
+func (r *DynamicIdentifierRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
+	return r.CheckBound(seg, node, parser.Bind(node, schema), schema)
+}
+
+// CheckBound reports the identifier parameters of the template that fall
+// in the statement, using its byte range from the binding
+func (r *DynamicIdentifierRule) CheckBound(seg *model.SQLSegment, node ast.StmtNode, binding *parser.Binding, schema *model.SchemaCtx) ([]model.Issue, error) {
+	if !seg.Templated {
+		return nil, nil
+	}
+
+	var issues []model.Issue
+	start, end, ok := binding.Span()
+	if !ok {
+		start, end = 0, len(seg.SQL) // A single statement
+	}
+	for _, p := range seg.TemplateParams {
+		if p.Kind != model.TemplateIdentifier || p.Offset < start || p.Offset >= end {
+			continue
+		}
+		issues = append(issues, model.Issue{
+			Type:       "DYNAMIC_IDENTIFIER",
+			Level:      model.RiskLevelWarning,
+			Message:    fmt.Sprintf("Table or column name is interpolated with %s, so the argument becomes part of the SQL.", p.Verb),
+			Suggestion: "Pick the name from a fixed allow-list before formatting it in; pass values as bind parameters (?) instead.",
+			Segment:    *seg,
+			Offset:     p.Offset,
+		})
+	}
+	return issues, nil
+}
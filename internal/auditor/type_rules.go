@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"sql-check/internal/model"
 	"sql-check/internal/parser"
-	"strings"
 
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/test_driver"
@@ -15,31 +14,25 @@ type ImplicitConversionRule struct{}
 
 func (r *ImplicitConversionRule) Name() string { return "implicit_conversion" }
 
-func (r *ImplicitConversionRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
-	var issues []model.Issue
+func (r *ImplicitConversionRule) Description() string { return "Comparison between a column and a value of another type prevents index use" }
 
-	// Find the table name first (reusing logic from IndexMissRule would be better, but duplication for now is safer)
-	// Find the table name first
-	tables := parser.ExtractTableNames(node)
-	var tableName string
-	if len(tables) > 0 {
-		tableName = tables[0]
-	}
+func (r *ImplicitConversionRule) DefaultLevel() model.RiskLevel { return model.RiskLevelWarning }
 
-	if tableName == "" {
-		return nil, nil
-	}
+func (r *ImplicitConversionRule) SizeAware() {}
 
-	table, ok := schema.Tables[tableName]
-	if !ok {
-		return nil, nil
-	}
+func (r *ImplicitConversionRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
+	return r.CheckBound(seg, node, parser.Bind(node, schema), schema)
+}
+
+// CheckBound checks the comparisons of every table of the statement, with
+// columns resolved through aliases
+func (r *ImplicitConversionRule) CheckBound(seg *model.SQLSegment, node ast.StmtNode, binding *parser.Binding, schema *model.SchemaCtx) ([]model.Issue, error) {
+	var issues []model.Issue
 
 	v := &typeVisitor{
-		issues:    &issues,
-		seg:       seg,
-		table:     table,
-		columns:   table.Columns,
+		issues:  &issues,
+		seg:     seg,
+		binding: binding,
 	}
 	node.Accept(v)
 
@@ -49,8 +42,7 @@ func (r *ImplicitConversionRule) Check(seg *model.SQLSegment, node ast.StmtNode,
 type typeVisitor struct {
 	issues  *[]model.Issue
 	seg     *model.SQLSegment
-	table   *model.Table
-	columns map[string]*model.Column
+	binding *parser.Binding
 }
 
 func (v *typeVisitor) Enter(in ast.Node) (ast.Node, bool) {
@@ -60,12 +52,12 @@ func (v *typeVisitor) Enter(in ast.Node) (ast.Node, bool) {
 		rVal, rOk := binOp.R.(*test_driver.ValueExpr)
 		
 		if lOk && rOk {
-			v.checkMismatch(lCol.Name.Name.O, rVal)
+			v.checkMismatch(lCol, rVal, binOp.OriginTextPosition())
 		} else {
 			lVal, lOk := binOp.L.(*test_driver.ValueExpr)
 			rCol, rOk := binOp.R.(*ast.ColumnNameExpr)
 			if lOk && rOk {
-				v.checkMismatch(rCol.Name.Name.O, lVal)
+				v.checkMismatch(rCol, lVal, binOp.OriginTextPosition())
 			}
 		}
 	}
@@ -76,28 +68,29 @@ func (v *typeVisitor) Leave(in ast.Node) (ast.Node, bool) {
 	return in, true
 }
 
-func (v *typeVisitor) checkMismatch(colName string, valExpr *test_driver.ValueExpr) {
-	colDef, ok := v.columns[colName]
-	if !ok {
+// checkMismatch reports a comparison at offset in the SQL between colExpr and valExpr
+func (v *typeVisitor) checkMismatch(colExpr *ast.ColumnNameExpr, valExpr *test_driver.ValueExpr, offset int) {
+	bound := v.binding.Column(colExpr)
+	if bound == nil || bound.Column == nil {
 		return
 	}
+	colDef, colName := bound.Column, bound.Name
 
-	colType := strings.ToUpper(colDef.Type)
-	isStringCol := strings.Contains(colType, "CHAR") || strings.Contains(colType, "TEXT")
-	
 	// ValueExpr Kind inspection
 	val := valExpr.GetValue()
 	
 	// Check: String Column compared with Int Value
-	if isStringCol {
+	if colDef.IsString() {
 		switch val.(type) {
-		case int, int64, float64:
+		case int, int64, uint64, float64, *test_driver.MyDecimal:
 			*v.issues = append(*v.issues, model.Issue{
 				Type:       "IMPLICIT_CONVERSION",
 				Level:      model.RiskLevelWarning,
 				Message:    fmt.Sprintf("Explicit implicit conversion detected: String column '%s' compared with Number.", colName),
 				Suggestion: "Quote the number to avoid implicit conversion and index invalidation (e.g., '123' instead of 123).",
 				Segment:    *v.seg,
+				Offset:     offset,
+				Table:      bound.Ref.Table,
 			})
 		}
 	}
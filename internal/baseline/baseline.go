@@ -0,0 +1,103 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sql-check/internal/model"
+	"time"
+)
+
+// Version is the version of the baseline file format
+const Version = 1
+
+// DefaultFile is the baseline file name used when none is given
+const DefaultFile = ".sql-check-baseline.json"
+
+// Baseline is a set of known issues that should not fail a check
+type Baseline struct {
+	Version     int       `json:"version"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Issues      []Entry   `json:"issues"`
+}
+
+// Entry is a known issue. Only the fingerprint is used for matching, the
+// other fields help reviewers read the file.
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`
+	Type        string `json:"type"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Message     string `json:"message"`
+}
+
+// New creates a baseline of issues. File paths are recorded relative to
+// root so the baseline does not depend on where the repository is checked out.
+// Suppressed issues are skipped as they are already silenced.
+func New(issues []model.Issue, root string) *Baseline {
+	b := &Baseline{
+		Version:     Version,
+		GeneratedAt: time.Now().UTC(),
+		Issues:      make([]Entry, 0, len(issues)),
+	}
+	for _, issue := range issues {
+		if issue.Suppressed {
+			continue
+		}
+		issue = issue.RelativeTo(root)
+		b.Issues = append(b.Issues, Entry{
+			Fingerprint: issue.Fingerprint(),
+			Type:        issue.Type,
+			File:        issue.Segment.Location.FilePath,
+			Line:        issue.Segment.Location.Line,
+			Message:     issue.Message,
+		})
+	}
+	return b
+}
+
+// Load reads a baseline file
+func Load(path string) (*Baseline, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Baseline{}
+	if err := json.Unmarshal(content, b); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %w", path, err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s", b.Version, path)
+	}
+	return b, nil
+}
+
+// Save writes the baseline as indented JSON
+func (b *Baseline) Save(path string) error {
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// Filter removes the issues known to the baseline. An issue recorded once
+// in the baseline only hides one occurrence, so new duplicates still show up.
+func (b *Baseline) Filter(issues []model.Issue, root string) (kept []model.Issue, known int) {
+	remaining := make(map[string]int, len(b.Issues))
+	for _, entry := range b.Issues {
+		remaining[entry.Fingerprint]++
+	}
+
+	for _, issue := range issues {
+		fp := issue.RelativeTo(root).Fingerprint()
+		if !issue.Suppressed && remaining[fp] > 0 {
+			remaining[fp]--
+			known++
+			continue
+		}
+		kept = append(kept, issue)
+	}
+	return kept, known
+}
@@ -0,0 +1,56 @@
+package baseline
+
+import (
+	"path/filepath"
+	"sql-check/internal/model"
+	"testing"
+)
+
+func issue(typ, sql, file string, line int) model.Issue {
+	return model.Issue{
+		Type: typ,
+		Segment: model.SQLSegment{
+			SQL:      sql,
+			Location: model.Location{FilePath: file, Line: line},
+		},
+	}
+}
+
+func TestBaseline_Filter(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "repo", "users.go")
+
+	b := New([]model.Issue{
+		issue("SELECT_STAR", "SELECT * FROM users", file, 10),
+		issue("INDEX_MISS", "SELECT id FROM users WHERE name = ?", file, 20),
+	}, root)
+
+	path := filepath.Join(root, DefaultFile)
+	if err := b.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded.Issues) != 2 || loaded.Issues[0].File != "repo/users.go" {
+		t.Fatalf("Unexpected baseline %+v", loaded.Issues)
+	}
+
+	current := []model.Issue{
+		// Moved down and reformatted: still known
+		issue("SELECT_STAR", "SELECT *\n\tFROM users", file, 42),
+		// Same SQL, new occurrence: reported
+		issue("SELECT_STAR", "SELECT * FROM users", file, 50),
+		// Same SQL in another file: reported
+		issue("INDEX_MISS", "SELECT id FROM users WHERE name = ?", filepath.Join(root, "repo", "admin.go"), 20),
+	}
+
+	kept, known := loaded.Filter(current, root)
+	if known != 1 {
+		t.Errorf("Expected 1 known issue, got %d", known)
+	}
+	if len(kept) != 2 || kept[0].Segment.Location.Line != 50 {
+		t.Errorf("Unexpected remaining issues %+v", kept)
+	}
+}
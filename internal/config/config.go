@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the config file names looked up in the scan root, in order
+var FileNames = []string{".sql-check.yaml", ".sql-check.yml"}
+
+// Config is the project configuration read from .sql-check.yaml
+type Config struct {
+	// Schema lists the schema files to load, optionally as "database=path"
+	Schema []string `yaml:"schema"`
+	// LowerCaseTableNames is the server's lower_case_table_names (default 1)
+	LowerCaseTableNames *int `yaml:"lower_case_table_names"`
+	// DefaultDatabase resolves unqualified table names found in several databases
+	DefaultDatabase string `yaml:"default_database"`
+	// Stats is the table statistics file
+	Stats string `yaml:"stats"`
+	// TableSize sets the row counts that change the level of size-aware rules
+	TableSize TableSize `yaml:"table_size"`
+	// Extensions lists the file extensions to scan (without dot)
+	Extensions []string `yaml:"extensions"`
+	// Excludes lists glob patterns excluded from the scan
+	Excludes []string `yaml:"excludes"`
+	// Workers is the number of concurrent extraction workers
+	Workers int `yaml:"workers"`
+	// ParseErrorLevel is the level of PARSE_ERROR issues
+	ParseErrorLevel string `yaml:"parse_error_level"`
+	// FailOn is the lowest level that makes the check fail (or "none")
+	FailOn string `yaml:"fail_on"`
+	// Baseline is the baseline file of known issues
+	Baseline string `yaml:"baseline"`
+	// Rules holds per-rule settings keyed by Rule.Name()
+	Rules map[string]RuleConfig `yaml:"rules"`
+
+	// Path of the file the config was loaded from, empty for defaults
+	Path string `yaml:"-"`
+}
+
+// RuleConfig holds the settings of a single rule
+type RuleConfig struct {
+	Enabled *bool                  `yaml:"enabled"`
+	Level   string                 `yaml:"level"`  // Overrides the level of the rule's issues
+	Params  map[string]interface{} `yaml:"params"` // Rule specific parameters
+}
+
+// TableSize holds the row count thresholds of size-aware rules. Issues on
+// tables below Small are downgraded, on tables of Large rows or more escalated.
+// Unset values keep the defaults, 0 disables the threshold.
+type TableSize struct {
+	Small *int64 `yaml:"small"`
+	Large *int64 `yaml:"large"`
+}
+
+// IsEnabled reports whether the rule should run. Rules are enabled unless
+// explicitly disabled.
+func (rc RuleConfig) IsEnabled() bool {
+	return rc.Enabled == nil || *rc.Enabled
+}
+
+// Load reads a config file. Relative paths are resolved against the
+// directory of the file.
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	cfg.Path = path
+
+	dir := filepath.Dir(path)
+	for i, p := range cfg.Schema {
+		if database, path := SplitSchemaPath(p); database != "" {
+			cfg.Schema[i] = database + "=" + resolve(dir, path)
+		} else {
+			cfg.Schema[i] = resolve(dir, p)
+		}
+	}
+	if cfg.Baseline != "" {
+		cfg.Baseline = resolve(dir, cfg.Baseline)
+	}
+	if cfg.Stats != "" {
+		cfg.Stats = resolve(dir, cfg.Stats)
+	}
+
+	return cfg, nil
+}
+
+// Discover looks for a config file in root (or the directory of root if it
+// is a file). It returns an empty config if there is none.
+func Discover(root string) (*Config, error) {
+	dir := root
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		dir = filepath.Dir(root)
+	}
+
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return Load(path)
+		}
+	}
+
+	return &Config{}, nil
+}
+
+// Validate checks that every configured rule is one of known
+func (c *Config) Validate(known []string) error {
+	names := make(map[string]bool, len(known))
+	for _, name := range known {
+		names[name] = true
+	}
+
+	var unknown []string
+	for name := range c.Rules {
+		if !names[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown rules in config: %v (known rules: %v)", unknown, known)
+	}
+	return nil
+}
+
+// resolve makes a relative path relative to dir
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// SplitSchemaPath splits a "database=path" schema argument. database is
+// empty for a plain path.
+func SplitSchemaPath(arg string) (database, path string) {
+	if db, path, ok := strings.Cut(arg, "="); ok && db != "" && !strings.ContainsAny(db, `/\.`) {
+		return db, path
+	}
+	return "", arg
+}
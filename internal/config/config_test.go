@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+schema:
+  - db/schema.sql
+stats: db/stats.yaml
+table_size:
+  small: 0
+extensions: [go, sql]
+excludes: [vendor, migrations]
+workers: 4
+rules:
+  deep_pagination:
+    level: fatal
+    params:
+      threshold: 1000
+  select_star:
+    enabled: false
+`
+	if err := os.WriteFile(filepath.Join(dir, ".sql-check.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Discover(dir)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	if cfg.Path != filepath.Join(dir, ".sql-check.yaml") {
+		t.Errorf("Unexpected config path %s", cfg.Path)
+	}
+	if len(cfg.Schema) != 1 || cfg.Schema[0] != filepath.Join(dir, "db/schema.sql") {
+		t.Errorf("Schema path not resolved against config dir: %v", cfg.Schema)
+	}
+	if cfg.Stats != filepath.Join(dir, "db/stats.yaml") {
+		t.Errorf("Stats path not resolved against config dir: %v", cfg.Stats)
+	}
+	if cfg.TableSize.Small == nil || *cfg.TableSize.Small != 0 || cfg.TableSize.Large != nil {
+		t.Errorf("Unexpected table_size %+v", cfg.TableSize)
+	}
+	if cfg.Workers != 4 || len(cfg.Extensions) != 2 || len(cfg.Excludes) != 2 {
+		t.Errorf("Unexpected scan settings %+v", cfg)
+	}
+
+	pagination := cfg.Rules["deep_pagination"]
+	if !pagination.IsEnabled() || pagination.Level != "fatal" || pagination.Params["threshold"] != 1000 {
+		t.Errorf("Unexpected deep_pagination settings %+v", pagination)
+	}
+	if cfg.Rules["select_star"].IsEnabled() {
+		t.Errorf("select_star should be disabled")
+	}
+	if !cfg.Rules["index_miss"].IsEnabled() {
+		t.Errorf("Rules without settings should be enabled")
+	}
+
+	if err := cfg.Validate([]string{"deep_pagination", "select_star"}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := cfg.Validate([]string{"deep_pagination"}); err == nil {
+		t.Errorf("Validate() should reject unknown rule select_star")
+	}
+}
+
+func TestDiscover_NoFile(t *testing.T) {
+	cfg, err := Discover(t.TempDir())
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if cfg.Path != "" || len(cfg.Rules) != 0 {
+		t.Errorf("Expected empty config, got %+v", cfg)
+	}
+}
@@ -0,0 +1,233 @@
+// Package diff reads unified diffs to find the lines a change touches.
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sql-check/internal/model"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches "@@ -12,3 +14,5 @@", counts default to 1 when omitted
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Range is an inclusive range of lines
+type Range struct {
+	Start, End int
+}
+
+// Changes holds the changed lines of the new version of each file
+type Changes struct {
+	files map[string][]Range // by absolute path
+}
+
+// Parse reads a unified diff (as written by git diff or diff -u). Paths in
+// the diff are relative to root.
+func Parse(r io.Reader, root string) (*Changes, error) {
+	absRoot, err := absPath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Changes{files: make(map[string][]Range)}
+	var (
+		file      string // Absolute path of the current file, empty if deleted
+		gitFormat bool   // Paths carry a/ and b/ prefixes
+		oldPath   string // Path of the last --- line
+		oldLeft   int    // Lines of the current hunk still to read
+		newLeft   int
+		newLine   int // Line number of the next line in the new file
+	)
+
+	mark := func(line int) {
+		if file != "" && line > 0 {
+			c.files[file] = append(c.files[file], Range{line, line})
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+
+		// Inside a hunk every line is content, even if it looks like a header
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				mark(newLine)
+				newLine++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				// A deletion touches the lines around it
+				mark(newLine - 1)
+				mark(newLine)
+				oldLeft--
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				newLine++
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			gitFormat = true
+		case strings.HasPrefix(line, "--- "):
+			oldPath = parsePath(line[4:])
+		case strings.HasPrefix(line, "+++ "):
+			path := parsePath(line[4:])
+			if path == "/dev/null" {
+				file = ""
+				continue
+			}
+			if gitFormat || strings.HasPrefix(oldPath, "a/") || oldPath == "/dev/null" {
+				path = strings.TrimPrefix(path, "b/")
+			}
+			file = filepath.Join(absRoot, filepath.FromSlash(path))
+		case strings.HasPrefix(line, "@@ "):
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			oldLeft = count(m[2])
+			newLine, _ = strconv.Atoi(m[3])
+			newLeft = count(m[4])
+			if newLeft == 0 {
+				// Pure deletion: the new side starts after line newLine
+				newLine++
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	for path, ranges := range c.files {
+		c.files[path] = merge(ranges)
+	}
+	return c, nil
+}
+
+// FromGit diffs the working tree of the repository containing dir against
+// the merge base of baseRef and HEAD, so changes made on the base branch
+// since are not included.
+func FromGit(baseRef, dir string) (*Changes, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	base, err := git(dir, "merge-base", baseRef, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(dir, "diff", "--no-color", "--no-ext-diff", "--unified=0", strings.TrimSpace(string(base)))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(out), strings.TrimSpace(string(root)))
+}
+
+// Touches reports whether any of the lines start to end of path changed
+func (c *Changes) Touches(path string, start, end int) bool {
+	abs, err := absPath(path)
+	if err != nil {
+		return false
+	}
+	for _, r := range c.files[abs] {
+		if r.Start <= end && start <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter keeps the issues whose segment intersects a changed line. dropped
+// is the number of issues removed.
+func (c *Changes) Filter(issues []model.Issue) (kept []model.Issue, dropped int) {
+	for _, issue := range issues {
+		loc := issue.Segment.Location
+		if c.Touches(loc.FilePath, loc.Line, loc.LastLine()) {
+			kept = append(kept, issue)
+		} else {
+			dropped++
+		}
+	}
+	return kept, dropped
+}
+
+// Files returns the number of files with changed lines
+func (c *Changes) Files() int {
+	return len(c.files)
+}
+
+// absPath makes path absolute and resolves symlinks, so paths from git and
+// from the scan compare equal
+func absPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	return abs, nil
+}
+
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// parsePath extracts the path of a ---/+++ line, which may be quoted or
+// followed by a tab and a timestamp
+func parsePath(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func count(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// merge sorts ranges and joins overlapping or adjacent ones
+func merge(ranges []Range) []Range {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	var out []Range
+	for _, r := range ranges {
+		if n := len(out); n > 0 && r.Start <= out[n-1].End+1 {
+			if r.End > out[n-1].End {
+				out[n-1].End = r.End
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
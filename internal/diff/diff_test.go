@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sql-check/internal/model"
+	"strings"
+	"testing"
+)
+
+const sample = `diff --git a/store/users.go b/store/users.go
+index 1111111..2222222 100644
+--- a/store/users.go
++++ b/store/users.go
+@@ -10,0 +11,2 @@ func List() {
++	q := "SELECT * FROM users"
++	_ = q
+@@ -30,2 +31,0 @@ func Delete() {
+--- a removed SQL comment
+-	db.Exec("DELETE FROM users")
+@@ -50 +49 @@ func Count() {
+-	q := "SELECT COUNT(*) FROM users"
++	q := "SELECT COUNT(id) FROM users"
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package store
+-
+`
+
+func TestParse(t *testing.T) {
+	root := t.TempDir()
+	c, err := Parse(strings.NewReader(sample), root)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	file := filepath.Join(root, "store", "users.go")
+	tests := []struct {
+		start, end int
+		want       bool
+	}{
+		{11, 11, true},  // Added
+		{12, 12, true},  // Added
+		{13, 13, false}, // Unchanged
+		{5, 10, false},  // Before the addition
+		{8, 15, true},   // Multi-line segment around the addition
+		{30, 30, false},
+		{31, 31, true}, // Next to the deletion
+		{32, 32, true},
+		{40, 45, false},
+		{49, 49, true}, // Modified
+	}
+	for _, tt := range tests {
+		if got := c.Touches(file, tt.start, tt.end); got != tt.want {
+			t.Errorf("Touches(%d, %d) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+	if c.Files() != 1 {
+		t.Errorf("Expected 1 changed file, got %d", c.Files())
+	}
+}
+
+func TestParse_PlainDiff(t *testing.T) {
+	root := t.TempDir()
+	plain := "--- users.sql\t2024-01-01 10:00:00\n+++ users.sql\t2024-01-02 10:00:00\n@@ -1,3 +1,3 @@\n SELECT 1;\n-SELECT 2;\n+SELECT 3;\n SELECT 4;\n"
+	c, err := Parse(strings.NewReader(plain), root)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	file := filepath.Join(root, "users.sql")
+	if !c.Touches(file, 2, 2) {
+		t.Error("Expected line 2 to be changed")
+	}
+	if c.Touches(file, 4, 4) {
+		t.Error("Expected context line 4 to be unchanged")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	root := t.TempDir()
+	c, err := Parse(strings.NewReader(sample), root)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	file := filepath.Join(root, "store", "users.go")
+	issues := []model.Issue{
+		{Type: "SELECT_STAR", Segment: model.SQLSegment{Location: model.Location{FilePath: file, Line: 11}}},
+		{Type: "INDEX_MISS", Segment: model.SQLSegment{Location: model.Location{FilePath: file, Line: 1}}},
+		// Starts before the change but spans it
+		{Type: "INDEX_MISS", Segment: model.SQLSegment{Location: model.Location{FilePath: file, Line: 45, EndLine: 49}}},
+		{Type: "SELECT_STAR", Segment: model.SQLSegment{Location: model.Location{FilePath: filepath.Join(root, "other.go"), Line: 11}}},
+	}
+	kept, dropped := c.Filter(issues)
+	if dropped != 2 || len(kept) != 2 || kept[1].Segment.Location.Line != 45 {
+		t.Errorf("Unexpected filter result %+v (dropped %d)", kept, dropped)
+	}
+}
+
+func TestFromGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "q.sql"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	write("SELECT 1;\nSELECT 2;\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "base")
+	run("tag", "base")
+	write("SELECT 1;\nSELECT 2;\nSELECT 3;\n")
+
+	c, err := FromGit("base", dir)
+	if err != nil {
+		t.Fatalf("FromGit() error = %v", err)
+	}
+	file := filepath.Join(dir, "q.sql")
+	if c.Touches(file, 1, 2) || !c.Touches(file, 3, 3) {
+		t.Errorf("Unexpected changes %+v", c.files)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"sql-check/internal/model"
+	"sql-check/internal/parser"
 	"strings"
 )
 
@@ -53,6 +54,13 @@ func (e *RegexExtractor) Extract(filePath string, content []byte) ([]model.SQLSe
 		return line
 	}
 
+	getColumn := func(idx int) int {
+		return idx - strings.LastIndexByte(text[:idx], '\n')
+	}
+
+	// Inline "sql-check:ignore" comments, keyed by line
+	suppressions := lineSuppressions(text)
+
 	for _, re := range []*regexp.Regexp{doubleQuoteSQL, singleQuoteSQL, backTickSQL} {
 		// FindStringIndex returns [start, end] byte offsets
 		matches := re.FindAllStringIndex(text, -1)
@@ -63,14 +71,23 @@ func (e *RegexExtractor) Extract(filePath string, content []byte) ([]model.SQLSe
 			if len(matchedStr) >= 2 {
 				// Strip quotes
 				sqlContent := matchedStr[1 : len(matchedStr)-1]
+				line := getLineNo(start)
+				endLine := getLineNo(end)
 				
 				segments = append(segments, model.SQLSegment{
 					SQL: sqlContent,
 					Location: model.Location{
-						FilePath: filePath,
-						Line:     getLineNo(start),
+						FilePath:  filePath,
+						Line:      line,
+						Column:    getColumn(start),
+						EndLine:   endLine,
+						EndColumn: getColumn(end),
 					},
-					Language: "detected",
+					Language:     "detected",
+					Confidence:   parser.Confidence(sqlContent),
+					Suppressions: suppressionsFor(suppressions, line, endLine),
+					// The SQL starts after the opening quote
+					SourceMap: []model.SourceSpan{{Line: line, Column: getColumn(start + 1), Verbatim: true}},
 				})
 			}
 		}
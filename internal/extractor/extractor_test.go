@@ -78,3 +78,27 @@ func TestRegexExtractor_Extract(t *testing.T) {
 		})
 	}
 }
+
+func TestRegexExtractor_Suppressions(t *testing.T) {
+	content := "# sql-check:ignore SELECT_STAR\ncursor.execute(\"SELECT * FROM users\")\ncursor.execute(\"SELECT * FROM orders\")"
+
+	segments, err := NewRegexExtractor().Extract("test.py", []byte(content))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 segments, got %d", len(segments))
+	}
+	if len(segments[0].Suppressions) != 1 || len(segments[1].Suppressions) != 0 {
+		t.Errorf("Unexpected suppressions %+v / %+v", segments[0].Suppressions, segments[1].Suppressions)
+	}
+}
+
+func TestLineSuppressions_SameLine(t *testing.T) {
+	text := "-- sql-check:ignore UNSAFE_DELETE\nDELETE FROM tmp; -- sql-check:ignore SELECT_STAR\n"
+
+	found := lineSuppressions(text)
+	if len(found[2]) != 2 || found[2][0].Types[0] != "UNSAFE_DELETE" || found[2][1].Types[0] != "SELECT_STAR" {
+		t.Errorf("Expected both directives on line 2, got %+v", found)
+	}
+}
@@ -0,0 +1,337 @@
+package extractor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sql-check/internal/model"
+	sqlparser "sql-check/internal/parser"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sqlPrefix matches strings that start like a DML statement
+var sqlPrefix = regexp.MustCompile(`(?is)^\s*(?:SELECT|INSERT|UPDATE|DELETE)\b`)
+
+// fragmentConfidence scales the confidence of literals that are only part
+// of a concatenation with runtime values, as they are rarely complete SQL.
+const fragmentConfidence = 0.4
+
+// GoExtractor understands Go source code. Unlike RegexExtractor it folds
+// string concatenations and resolves constants declared in the same package.
+type GoExtractor struct {
+	mu     sync.Mutex
+	consts map[string]map[string]string // package dir -> const name -> value
+}
+
+func NewGoExtractor() *GoExtractor {
+	return &GoExtractor{
+		consts: make(map[string]map[string]string),
+	}
+}
+
+func (e *GoExtractor) Extract(filePath string, content []byte) ([]model.SQLSegment, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, content, parser.SkipObjectResolution|parser.ParseComments)
+	if err != nil {
+		// Broken or partial files still deserve a best-effort scan
+		return NewRegexExtractor().Extract(filePath, content)
+	}
+
+	// Inline "sql-check:ignore" comments, keyed by line
+	suppressions := make(map[int][]model.Suppression)
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			pos := fset.Position(c.Slash)
+			if s, ok := parseSuppression(c.Text, pos.Line); ok {
+				lineStart := bytes.LastIndexByte(content[:pos.Offset], '\n') + 1
+				standalone := len(bytes.TrimSpace(content[lineStart:pos.Offset])) == 0
+				line := suppressedLine(pos.Line, standalone)
+				suppressions[line] = append(suppressions[line], s)
+			}
+		}
+	}
+	suppressed := func(node ast.Node) []model.Suppression {
+		return suppressionsFor(suppressions, fset.Position(node.Pos()).Line, fset.Position(node.End()).Line)
+	}
+
+	// Constants visible at file level: package level (all files) + this
+	// file's. Function bodies add their own local ones while walked.
+	consts := make(map[string]string)
+	for name, val := range e.packageConsts(filePath, file.Name.Name) {
+		consts[name] = val
+	}
+	collectPackageConsts(file, consts)
+	fmtName := fmtImportName(file)
+
+	var segments []model.SQLSegment
+	partial := make(map[ast.Node]bool) // operands of unresolvable concatenations
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ImportSpec:
+			return false
+		case *ast.FuncDecl, *ast.FuncLit:
+			body := funcBody(node)
+			if body == nil {
+				return false
+			}
+			outer := consts
+			consts = localConsts(body, outer)
+			ast.Inspect(body, visit)
+			consts = outer
+			return false
+		case *ast.CallExpr:
+			formatArg, idx, ok := templateFormatArg(node, fmtName)
+			if !ok {
+				return true
+			}
+			format, ok := evalString(formatArg, consts)
+			if !ok || !sqlPrefix.MatchString(format) {
+				return true
+			}
+			sql, params, aligns := expandTemplateAligned(format)
+			segments = append(segments, model.SQLSegment{
+				SQL:            sql,
+				Location:       location(fset, filePath, formatArg),
+				Language:       "go",
+				Confidence:     sqlparser.Confidence(sql),
+				Templated:      len(params) > 0,
+				TemplateParams: params,
+				Suppressions:   suppressed(node),
+				SourceMap:      templateSourceMap(format, sourceSpans(formatArg, fset, consts), aligns),
+			})
+			// The format string is handled, but other arguments may hold SQL too
+			for i, arg := range node.Args {
+				if i != idx {
+					ast.Inspect(arg, visit)
+				}
+			}
+			return false
+		case *ast.BinaryExpr, *ast.BasicLit, *ast.ParenExpr:
+			sql, ok := evalString(node.(ast.Expr), consts)
+			if !ok {
+				// Not fully resolvable, look for foldable parts inside
+				if bin, isBin := node.(*ast.BinaryExpr); isBin && bin.Op == token.ADD {
+					partial[bin.X] = true
+					partial[bin.Y] = true
+				}
+				return true
+			}
+			if sqlPrefix.MatchString(sql) {
+				confidence := sqlparser.Confidence(sql)
+				if partial[node] {
+					confidence *= fragmentConfidence
+				}
+				segments = append(segments, model.SQLSegment{
+					SQL:          sql,
+					Location:     location(fset, filePath, node),
+					Language:     "go",
+					Confidence:   confidence,
+					Suppressions: suppressed(node),
+					SourceMap:    sourceSpans(node.(ast.Expr), fset, consts),
+				})
+			}
+			return false
+		}
+		return true
+	}
+	ast.Inspect(file, visit)
+
+	return segments, nil
+}
+
+// packageConsts returns the string constants declared at package level in
+// the other files of the package. Results are cached per directory.
+func (e *GoExtractor) packageConsts(filePath, pkgName string) map[string]string {
+	dir := filepath.Dir(filePath)
+	key := dir + "#" + pkgName
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if consts, ok := e.consts[key]; ok {
+		return consts
+	}
+
+	consts := make(map[string]string)
+	entries, err := os.ReadDir(dir)
+	if err == nil {
+		fset := token.NewFileSet()
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".go" {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+			if err != nil || file.Name.Name != pkgName {
+				continue
+			}
+			collectPackageConsts(file, consts)
+		}
+	}
+
+	e.consts[key] = consts
+	return consts
+}
+
+// collectPackageConsts records top-level string constants of a file
+func collectPackageConsts(file *ast.File, consts map[string]string) {
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.CONST {
+			addConstSpecs(gen, consts)
+		}
+	}
+}
+
+// funcBody returns the body of a function declaration or literal, nil for
+// declarations without one
+func funcBody(node ast.Node) *ast.BlockStmt {
+	switch fn := node.(type) {
+	case *ast.FuncDecl:
+		return fn.Body
+	case *ast.FuncLit:
+		return fn.Body
+	}
+	return nil
+}
+
+// localConsts returns the constants visible in a function body: those of
+// the enclosing scope plus the ones declared in the body. Constants of
+// nested function literals are left to their own scope.
+func localConsts(body *ast.BlockStmt, outer map[string]string) map[string]string {
+	consts := make(map[string]string, len(outer))
+	for name, val := range outer {
+		consts[name] = val
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.GenDecl:
+			if node.Tok == token.CONST {
+				addConstSpecs(node, consts)
+			}
+			return false
+		}
+		return true
+	})
+	return consts
+}
+
+func addConstSpecs(gen *ast.GenDecl, consts map[string]string) {
+	// Constants may reference each other in any order, so iterate until
+	// no new value can be resolved.
+	for {
+		resolved := false
+		for _, spec := range gen.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for i, name := range vs.Names {
+				if i >= len(vs.Values) {
+					break
+				}
+				if _, done := consts[name.Name]; done {
+					continue
+				}
+				if val, ok := evalString(vs.Values[i], consts); ok {
+					consts[name.Name] = val
+					resolved = true
+				}
+			}
+		}
+		if !resolved {
+			return
+		}
+	}
+}
+
+// location returns the source range of node
+func location(fset *token.FileSet, filePath string, node ast.Node) model.Location {
+	start, end := fset.Position(node.Pos()), fset.Position(node.End())
+	return model.Location{
+		FilePath:  filePath,
+		Line:      start.Line,
+		Column:    start.Column,
+		EndLine:   end.Line,
+		EndColumn: end.Column,
+	}
+}
+
+// sourceSpans maps the value of a constant string expression, as folded by
+// evalString, back to the literals and constants it is made of.
+func sourceSpans(expr ast.Expr, fset *token.FileSet, consts map[string]string) []model.SourceSpan {
+	var spans []model.SourceSpan
+	offset := 0
+
+	var walk func(expr ast.Expr)
+	walk = func(expr ast.Expr) {
+		switch e := expr.(type) {
+		case *ast.BasicLit:
+			s, _ := strconv.Unquote(e.Value)
+			pos := fset.Position(e.Pos())
+			spans = append(spans, model.SourceSpan{
+				Offset: offset,
+				Line:   pos.Line,
+				Column: pos.Column + 1, // After the quote
+				// Escape sequences make interpreted strings differ from their source
+				Verbatim: e.Value[0] == '`' || !strings.Contains(e.Value, `\`),
+			})
+			offset += len(s)
+		case *ast.Ident:
+			// Constants point at where they are used
+			pos := fset.Position(e.Pos())
+			spans = append(spans, model.SourceSpan{Offset: offset, Line: pos.Line, Column: pos.Column})
+			offset += len(consts[e.Name])
+		case *ast.ParenExpr:
+			walk(e.X)
+		case *ast.BinaryExpr:
+			walk(e.X)
+			walk(e.Y)
+		}
+	}
+	walk(expr)
+	return spans
+}
+
+// evalString folds a constant string expression.
+// It returns false if any part of the expression is not a known string.
+func evalString(expr ast.Expr, consts map[string]string) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		if err != nil {
+			return "", false
+		}
+		return s, true
+	case *ast.Ident:
+		s, ok := consts[e.Name]
+		return s, ok
+	case *ast.ParenExpr:
+		return evalString(e.X, consts)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		l, ok := evalString(e.X, consts)
+		if !ok {
+			return "", false
+		}
+		r, ok := evalString(e.Y, consts)
+		if !ok {
+			return "", false
+		}
+		return l + r, true
+	}
+	return "", false
+}
@@ -0,0 +1,320 @@
+package extractor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sql-check/internal/model"
+	"strings"
+	"testing"
+)
+
+func TestGoExtractor_Extract(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "Plain literal",
+			content: `package repo
+func f() { db.Query("SELECT id FROM users") }`,
+			expected: []string{"SELECT id FROM users"},
+		},
+		{
+			name: "Concatenated literals",
+			content: `package repo
+func f() {
+	db.Query("SELECT id FROM users " +
+		"WHERE email = ?")
+}`,
+			expected: []string{"SELECT id FROM users WHERE email = ?"},
+		},
+		{
+			name: "Constant block",
+			content: `package repo
+const (
+	cols  = "id, name"
+	query = "SELECT " + cols + " FROM users WHERE id = ?"
+)`,
+			expected: []string{"SELECT id, name FROM users WHERE id = ?"},
+		},
+		{
+			name: "Constant used in concatenation",
+			content: `package repo
+const base = "SELECT id FROM users"
+func f() { db.Query(base + " WHERE id = ?") }`,
+			expected: []string{"SELECT id FROM users", "SELECT id FROM users WHERE id = ?"},
+		},
+		{
+			name: "Function constants stay in their function",
+			content: `package repo
+func f() {
+	const where = " WHERE id = ?"
+	db.Query("SELECT id FROM users" + where)
+}
+func g() { db.Query("DELETE FROM users" + where) }`,
+			expected: []string{"SELECT id FROM users WHERE id = ?", "DELETE FROM users"},
+		},
+		{
+			name: "Unresolvable concatenation keeps literal parts",
+			content: `package repo
+func f(id string) { db.Query("DELETE FROM users WHERE id = " + id) }`,
+			expected: []string{"DELETE FROM users WHERE id = "},
+		},
+		{
+			name:     "Raw string",
+			content:  "package repo\nvar q = `UPDATE users\n\tSET name = ?`",
+			expected: []string{"UPDATE users\n\tSET name = ?"},
+		},
+		{
+			name:     "No SQL",
+			content:  `package repo; var s = "SELECTING items is fun"`,
+			expected: nil,
+		},
+	}
+
+	extractor := NewGoExtractor()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := extractor.Extract("test.go", []byte(tt.content))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			var got []string
+			for _, seg := range segments {
+				got = append(got, seg.SQL)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Extract() got = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGoExtractor_PackageConstants(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"queries.go": "package repo\n\nconst userCols = \"id, email\"\n",
+		"repo.go":    "package repo\n\nfunc f() {\n\tdb.Query(\"SELECT \" + userCols + \" FROM users\")\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "repo.go")
+	segments, err := NewGoExtractor().Extract(path, []byte(files["repo.go"]))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	if len(segments) != 1 {
+		t.Fatalf("Expected 1 segment, got %d", len(segments))
+	}
+	if segments[0].SQL != "SELECT id, email FROM users" {
+		t.Errorf("Unexpected SQL %q", segments[0].SQL)
+	}
+	if segments[0].Location.Line != 4 {
+		t.Errorf("Expected line 4, got %d", segments[0].Location.Line)
+	}
+}
+
+func TestGoExtractor_Sprintf(t *testing.T) {
+	content := `package repo
+import "fmt"
+func f() {
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE id = %d AND name = '%s'", cols, table, id, name)
+	fmt.Fprintf(w, "DELETE FROM logs WHERE level IN (%s, %s) LIMIT %d", a, b, n)
+}`
+
+	segments, err := NewGoExtractor().Extract("test.go", []byte(content))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 segments, got %d", len(segments))
+	}
+
+	want := []struct {
+		sql   string
+		kinds []model.TemplateKind
+	}{
+		{
+			sql: "SELECT tpl_ident FROM tpl_ident WHERE id = 1 AND name = 'tpl'",
+			kinds: []model.TemplateKind{
+				model.TemplateIdentifier, model.TemplateIdentifier, model.TemplateNumber, model.TemplateString,
+			},
+		},
+		{
+			sql: "DELETE FROM logs WHERE level IN ('tpl', 'tpl') LIMIT 1",
+			kinds: []model.TemplateKind{
+				model.TemplateString, model.TemplateString, model.TemplateNumber,
+			},
+		},
+	}
+
+	for i, w := range want {
+		seg := segments[i]
+		if seg.SQL != w.sql {
+			t.Errorf("segment %d: got SQL %q, want %q", i, seg.SQL, w.sql)
+		}
+		if !seg.Templated {
+			t.Errorf("segment %d: expected Templated to be set", i)
+		}
+		var kinds []model.TemplateKind
+		for _, p := range seg.TemplateParams {
+			kinds = append(kinds, p.Kind)
+			if rest := strings.TrimPrefix(seg.SQL[p.Offset:], "'"); !strings.HasPrefix(rest, "tpl") && !strings.HasPrefix(rest, "1") {
+				t.Errorf("segment %d: param %s offset %d does not point at its value", i, p.Verb, p.Offset)
+			}
+		}
+		if !reflect.DeepEqual(kinds, w.kinds) {
+			t.Errorf("segment %d: got kinds %v, want %v", i, kinds, w.kinds)
+		}
+	}
+}
+
+func TestGoExtractor_SprintfOtherPackage(t *testing.T) {
+	content := `package repo
+import (
+	f "fmt"
+	"example.com/log"
+)
+func g() {
+	log.Sprintf("SELECT id FROM %s", table)
+	f.Sprintf("SELECT id FROM users WHERE id = %d", id)
+}`
+
+	segments, err := NewGoExtractor().Extract("test.go", []byte(content))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 segments, got %d", len(segments))
+	}
+	if segments[0].Templated || segments[0].SQL != "SELECT id FROM %s" {
+		t.Errorf("log.Sprintf should be read as a plain string, got %q", segments[0].SQL)
+	}
+	if !segments[1].Templated || segments[1].SQL != "SELECT id FROM users WHERE id = 1" {
+		t.Errorf("Renamed fmt import should be expanded, got %q", segments[1].SQL)
+	}
+}
+
+func TestExpandTemplate_Escapes(t *testing.T) {
+	sql, params := expandTemplate("SELECT id FROM users WHERE name LIKE '%%%s%%' AND age > %5.2f")
+	if sql != "SELECT id FROM users WHERE name LIKE '%tpl%' AND age > 1.0" {
+		t.Errorf("Unexpected SQL %q", sql)
+	}
+	if len(params) != 2 || params[1].Verb != "%5.2f" {
+		t.Errorf("Unexpected params %+v", params)
+	}
+}
+
+func TestGoExtractor_Suppressions(t *testing.T) {
+	content := `package repo
+func f() {
+	// sql-check:ignore UNSAFE_DELETE,SELECT_STAR reason="staging table"
+	db.Exec("DELETE FROM tmp_staging")
+	db.Exec("DELETE FROM users") // sql-check:ignore
+	db.Exec("SELECT * FROM users")
+}`
+
+	segments, err := NewGoExtractor().Extract("test.go", []byte(content))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(segments) != 3 {
+		t.Fatalf("Expected 3 segments, got %d", len(segments))
+	}
+
+	first := segments[0].Suppressions
+	if len(first) != 1 || first[0].Reason != "staging table" ||
+		!reflect.DeepEqual(first[0].Types, []string{"UNSAFE_DELETE", "SELECT_STAR"}) {
+		t.Errorf("Unexpected suppressions on first segment: %+v", first)
+	}
+	if second := segments[1].Suppressions; len(second) != 1 || len(second[0].Types) != 0 {
+		t.Errorf("Unexpected suppressions on second segment: %+v", second)
+	}
+	if third := segments[2].Suppressions; len(third) != 0 {
+		t.Errorf("Trailing comment must not leak to the next line: %+v", third)
+	}
+}
+
+func TestGoExtractor_SuppressionsSameLine(t *testing.T) {
+	content := `package repo
+func f() {
+	// sql-check:ignore UNSAFE_DELETE
+	db.Exec("DELETE FROM tmp_staging") // sql-check:ignore SELECT_STAR
+}`
+
+	segments, err := NewGoExtractor().Extract("test.go", []byte(content))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("Expected 1 segment, got %d", len(segments))
+	}
+	var types []string
+	for _, s := range segments[0].Suppressions {
+		types = append(types, s.Types...)
+	}
+	if !reflect.DeepEqual(types, []string{"UNSAFE_DELETE", "SELECT_STAR"}) {
+		t.Errorf("Both directives should apply, got %+v", segments[0].Suppressions)
+	}
+}
+
+func TestGoExtractor_SourcePositions(t *testing.T) {
+	content := "package repo; import \"fmt\"\n" +
+		"const cols = \"id, name\"\n" +
+		"func f(n int) {\n" +
+		"\tdb.Query(`\n" +
+		"\t\tSELECT id FROM users\n" +
+		"\t\tWHERE name = 1`)\n" +
+		"\tdb.Query(\"SELECT \" + cols + \" FROM users \" +\n" +
+		"\t\t\"WHERE name = 2\")\n" +
+		"\tdb.Query(fmt.Sprintf(\"SELECT id FROM users WHERE a = %d AND name = 3\", n))\n" +
+		"}"
+
+	segments, err := NewGoExtractor().Extract("test.go", []byte(content))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(segments) != 3 {
+		t.Fatalf("Expected 3 segments, got %d", len(segments))
+	}
+
+	tests := []struct {
+		find         string // Text in the segment's SQL
+		line, column int
+	}{
+		{"WHERE name = 1", 6, 3},
+		{"WHERE name = 2", 8, 4},
+		{"id, name", 7, 23}, // Constants map to where they are used
+		{"name = 3", 9, 62}, // After a substituted %d
+	}
+	for _, tt := range tests {
+		found := false
+		for i := range segments {
+			seg := &segments[i]
+			if offset := strings.Index(seg.SQL, tt.find); offset >= 0 {
+				found = true
+				if line, column := seg.Position(offset); line != tt.line || column != tt.column {
+					t.Errorf("%q: got %d:%d, want %d:%d", tt.find, line, column, tt.line, tt.column)
+				}
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%q not found in any segment", tt.find)
+		}
+	}
+
+	if loc := segments[0].Location; loc.Line != 4 || loc.Column != 11 || loc.EndLine != 6 || loc.EndColumn != 18 {
+		t.Errorf("Unexpected raw string location %+v", loc)
+	}
+}
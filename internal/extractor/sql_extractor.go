@@ -0,0 +1,107 @@
+package extractor
+
+import (
+	"sql-check/internal/model"
+	"sql-check/internal/parser"
+	"strings"
+)
+
+// SQLFileExtractor handles plain .sql files (scripts, migrations).
+// Every DML statement of the file becomes its own segment.
+type SQLFileExtractor struct {
+}
+
+func NewSQLFileExtractor() *SQLFileExtractor {
+	return &SQLFileExtractor{}
+}
+
+func (e *SQLFileExtractor) Extract(filePath string, content []byte) ([]model.SQLSegment, error) {
+	var segments []model.SQLSegment
+
+	text := string(content)
+	suppressions := lineSuppressions(text)
+
+	for _, span := range splitStatements(text) {
+		sql := text[span[0]:span[1]]
+		if !sqlPrefix.MatchString(sql) {
+			// DDL and session statements are not audited
+			continue
+		}
+
+		start := 1 + strings.Count(text[:span[0]], "\n")
+		end := start + strings.Count(sql, "\n")
+		segments = append(segments, model.SQLSegment{
+			SQL: sql,
+			Location: model.Location{
+				FilePath:  filePath,
+				Line:      start,
+				Column:    column(text, span[0]),
+				EndLine:   end,
+				EndColumn: column(text, span[1]),
+			},
+			Language:     "sql",
+			Confidence:   parser.Confidence(sql),
+			Suppressions: suppressionsFor(suppressions, start, end),
+		})
+	}
+
+	return segments, nil
+}
+
+// splitStatements returns the [start, end) byte ranges of the statements in
+// a SQL script. Ranges exclude the terminating semicolon as well as leading
+// whitespace and comments.
+func splitStatements(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+
+	flush := func(end int) {
+		if start >= 0 {
+			spans = append(spans, [2]int{start, end})
+			start = -1
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case strings.HasPrefix(text[i:], "--") || c == '#':
+			if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(text)
+			}
+			continue
+		case strings.HasPrefix(text[i:], "/*"):
+			if end := strings.Index(text[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(text)
+			}
+			continue
+		case c == ';':
+			flush(i)
+			continue
+		}
+
+		if start < 0 {
+			start = i
+		}
+		if c == '\'' || c == '"' || c == '`' {
+			i = parser.SkipQuoted(text, i)
+		}
+	}
+
+	// Trim trailing whitespace of an unterminated last statement
+	if start >= 0 {
+		flush(len(strings.TrimRight(text, " \t\r\n")))
+	}
+	return spans
+}
+
+// column returns the 1-based column of the byte at offset
+func column(text string, offset int) int {
+	return offset - strings.LastIndexByte(text[:offset], '\n')
+}
@@ -0,0 +1,53 @@
+package extractor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSQLFileExtractor_Extract(t *testing.T) {
+	content := `-- cleanup script
+CREATE TABLE tmp (id INT);
+
+-- sql-check:ignore UNSAFE_DELETE reason="staging table"
+DELETE FROM tmp_staging;
+UPDATE users
+SET name = 'a;b' -- trailing; comment
+WHERE id = 1;
+/* block */ SELECT 1`
+
+	segments, err := NewSQLFileExtractor().Extract("script.sql", []byte(content))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	var got []string
+	var lines []int
+	for _, seg := range segments {
+		got = append(got, seg.SQL)
+		lines = append(lines, seg.Location.Line)
+	}
+
+	want := []string{
+		"DELETE FROM tmp_staging",
+		"UPDATE users\nSET name = 'a;b' -- trailing; comment\nWHERE id = 1",
+		"SELECT 1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() got %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(lines, []int{5, 6, 9}) {
+		t.Errorf("Extract() got lines %v", lines)
+	}
+
+	if len(segments[0].Suppressions) != 1 {
+		t.Fatalf("Expected DELETE to be suppressed, got %+v", segments[0].Suppressions)
+	}
+	s := segments[0].Suppressions[0]
+	if !reflect.DeepEqual(s.Types, []string{"UNSAFE_DELETE"}) || s.Reason != "staging table" {
+		t.Errorf("Unexpected suppression %+v", s)
+	}
+	if len(segments[1].Suppressions) != 0 {
+		t.Errorf("UPDATE should not be suppressed")
+	}
+}
@@ -0,0 +1,91 @@
+package extractor
+
+import (
+	"regexp"
+	"sql-check/internal/model"
+	"strings"
+	"unicode"
+)
+
+// Suppression comments look like:
+//
+//	// sql-check:ignore UNSAFE_DELETE,SELECT_STAR reason="staging table"
+//	-- sql-check:ignore
+var (
+	suppressDirective = regexp.MustCompile(`sql-check:ignore\b(.*)`)
+	suppressReason    = regexp.MustCompile(`reason\s*=\s*"([^"]*)"`)
+)
+
+// commentMarkers start a comment in the languages handled by the text extractors
+var commentMarkers = []string{"//", "#", "--", "/*"}
+
+// parseSuppression parses a comment holding a sql-check:ignore directive
+func parseSuppression(comment string, line int) (model.Suppression, bool) {
+	m := suppressDirective.FindStringSubmatch(comment)
+	if m == nil {
+		return model.Suppression{}, false
+	}
+
+	s := model.Suppression{Line: line}
+	rest := m[1]
+	if r := suppressReason.FindStringSubmatchIndex(rest); r != nil {
+		s.Reason = rest[r[2]:r[3]]
+		rest = rest[:r[0]] + rest[r[1]:]
+	}
+	rest = strings.TrimSuffix(strings.TrimSpace(rest), "*/")
+
+	s.Types = strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	return s, true
+}
+
+// lineSuppressions finds sql-check:ignore comments in plain text, keyed by
+// the line they apply to. The directive must follow a comment marker.
+func lineSuppressions(text string) map[int][]model.Suppression {
+	found := make(map[int][]model.Suppression)
+	if !strings.Contains(text, "sql-check:ignore") {
+		return found
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		idx := strings.Index(line, "sql-check:ignore")
+		if idx < 0 {
+			continue
+		}
+		markerPos := -1
+		for _, marker := range commentMarkers {
+			if pos := strings.Index(line[:idx], marker); pos >= 0 && (markerPos < 0 || pos < markerPos) {
+				markerPos = pos
+			}
+		}
+		if markerPos < 0 {
+			continue
+		}
+		if s, ok := parseSuppression(line[idx:], i+1); ok {
+			standalone := strings.TrimSpace(line[:markerPos]) == ""
+			line := suppressedLine(i+1, standalone)
+			found[line] = append(found[line], s)
+		}
+	}
+	return found
+}
+
+// suppressedLine returns the line a comment on line applies to. A comment
+// alone on its line covers the next line, a trailing comment its own line.
+func suppressedLine(line int, standalone bool) int {
+	if standalone {
+		return line + 1
+	}
+	return line
+}
+
+// suppressionsFor returns the suppressions that apply to a segment spanning
+// lines start to end.
+func suppressionsFor(all map[int][]model.Suppression, start, end int) []model.Suppression {
+	var out []model.Suppression
+	for line := start; line <= end; line++ {
+		out = append(out, all[line]...)
+	}
+	return out
+}
@@ -0,0 +1,274 @@
+package extractor
+
+import (
+	"go/ast"
+	"sort"
+	"sql-check/internal/model"
+	"strings"
+)
+
+// Placeholder values substituted for printf verbs so that templated
+// queries can still be parsed and audited.
+const (
+	templateIdent  = "tpl_ident"
+	templateString = "tpl"
+	templateInt    = "1"
+	templateFloat  = "1.0"
+	templateBool   = "TRUE"
+)
+
+// valueKeywords are keywords after which an unquoted %s is a value, not an identifier
+var valueKeywords = map[string]bool{
+	"LIKE": true, "REGEXP": true, "RLIKE": true, "BETWEEN": true,
+	"IN": true, "VALUES": true, "LIMIT": true, "OFFSET": true,
+}
+
+// templateFormatArg returns the format argument of a fmt.Sprintf or
+// fmt.Fprintf call, and its index among the call arguments. fmtName is the
+// name the file imports "fmt" under (see fmtImportName).
+func templateFormatArg(call *ast.CallExpr, fmtName string) (ast.Expr, int, bool) {
+	var name string
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		pkg, ok := fun.X.(*ast.Ident)
+		if !ok || fmtName == "" || pkg.Name != fmtName {
+			return nil, 0, false
+		}
+		name = fun.Sel.Name
+	case *ast.Ident:
+		if fmtName != "." {
+			return nil, 0, false
+		}
+		name = fun.Name
+	default:
+		return nil, 0, false
+	}
+
+	idx := -1
+	switch name {
+	case "Sprintf":
+		idx = 0
+	case "Fprintf":
+		idx = 1
+	}
+	if idx < 0 || len(call.Args) <= idx {
+		return nil, 0, false
+	}
+	return call.Args[idx], idx, true
+}
+
+// fmtImportName returns the name a file refers to package fmt by: "fmt"
+// unless renamed, "." for a dot import, empty if it is not imported
+func fmtImportName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		if spec.Path.Value != `"fmt"` {
+			continue
+		}
+		if spec.Name != nil {
+			if spec.Name.Name == "_" {
+				continue
+			}
+			return spec.Name.Name
+		}
+		return "fmt"
+	}
+	return ""
+}
+
+// alignment records that the expanded SQL from offset out on is copied from
+// the format string from offset in on. It is not a copy if !verbatim, which
+// is the case for substituted verbs.
+type alignment struct {
+	out, in  int
+	verbatim bool
+}
+
+// expandTemplate replaces the printf verbs of format with typed placeholder
+// values. Numeric verbs become number literals, %s/%v/%q become an
+// identifier or a string depending on where they appear in the query.
+func expandTemplate(format string) (string, []model.TemplateParam) {
+	sql, params, _ := expandTemplateAligned(format)
+	return sql, params
+}
+
+// expandTemplateAligned is expandTemplate, also returning how the offsets of
+// the expanded SQL relate to those of format.
+func expandTemplateAligned(format string) (string, []model.TemplateParam, []alignment) {
+	var out strings.Builder
+	var params []model.TemplateParam
+	var aligns []alignment
+	var quote byte // current SQL quote character, 0 if outside quotes
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+
+		if c != '%' {
+			switch {
+			case quote != 0 && c == '\\' && quote != '`' && i+1 < len(format):
+				out.WriteByte(c)
+				i++
+				c = format[i]
+			case quote == 0 && (c == '\'' || c == '"' || c == '`'):
+				quote = c
+			case c == quote:
+				quote = 0
+			}
+			out.WriteByte(c)
+			continue
+		}
+
+		if i+1 < len(format) && format[i+1] == '%' {
+			out.WriteByte('%')
+			i++
+			aligns = append(aligns, alignment{out: out.Len(), in: i + 1, verbatim: true})
+			continue
+		}
+
+		end := verbEnd(format, i+1)
+		if end < 0 {
+			// Dangling '%', keep the rest untouched
+			out.WriteString(format[i:])
+			break
+		}
+		verb := format[i : end+1]
+
+		var kind model.TemplateKind
+		var value string
+		switch format[end] {
+		case 'd', 'b', 'o', 'x', 'X', 'U':
+			kind, value = model.TemplateNumber, templateInt
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			kind, value = model.TemplateNumber, templateFloat
+		case 't':
+			kind, value = model.TemplateNumber, templateBool
+		default:
+			switch {
+			case quote == '`':
+				kind, value = model.TemplateIdentifier, templateIdent
+			case quote != 0:
+				kind, value = model.TemplateString, templateString
+			case format[end] == 'q' || isValuePosition(out.String(), params):
+				kind, value = model.TemplateString, "'"+templateString+"'"
+			default:
+				kind, value = model.TemplateIdentifier, templateIdent
+			}
+		}
+
+		params = append(params, model.TemplateParam{
+			Verb:   verb,
+			Kind:   kind,
+			Offset: out.Len(),
+		})
+		aligns = append(aligns, alignment{out: out.Len(), in: i})
+		out.WriteString(value)
+		i = end
+		aligns = append(aligns, alignment{out: out.Len(), in: i + 1, verbatim: true})
+	}
+
+	return out.String(), params, aligns
+}
+
+// templateSourceMap maps the SQL expanded from a format string back to the
+// source, given the source map of the format string itself.
+func templateSourceMap(format string, formatMap []model.SourceSpan, aligns []alignment) []model.SourceSpan {
+	points := append([]alignment{{verbatim: true}}, aligns...)
+
+	// Where the format's own spans start in the expanded SQL
+	for _, sp := range formatMap {
+		a := alignment{verbatim: true}
+		for _, al := range aligns {
+			if al.in > sp.Offset {
+				break
+			}
+			a = al
+		}
+		if a.verbatim {
+			points = append(points, alignment{out: a.out + sp.Offset - a.in, in: sp.Offset, verbatim: true})
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].out < points[j].out })
+
+	src := model.SQLSegment{SQL: format, SourceMap: formatMap}
+	spans := make([]model.SourceSpan, 0, len(points))
+	for _, p := range points {
+		line, col := src.Position(p.in)
+		spans = append(spans, model.SourceSpan{
+			Offset:   p.out,
+			Line:     line,
+			Column:   col,
+			Verbatim: p.verbatim && spanAt(formatMap, p.in).Verbatim,
+		})
+	}
+	return spans
+}
+
+// spanAt returns the span of a source map holding offset
+func spanAt(spans []model.SourceSpan, offset int) model.SourceSpan {
+	span := model.SourceSpan{Verbatim: true}
+	for _, sp := range spans {
+		if sp.Offset > offset {
+			break
+		}
+		span = sp
+	}
+	return span
+}
+
+// verbEnd returns the index of the verb character of a printf directive
+// starting after '%' at position i, or -1 if there is none.
+func verbEnd(format string, i int) int {
+	for ; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case strings.IndexByte("+-# 0.*", c) >= 0 || (c >= '0' && c <= '9'):
+			continue
+		case c == '[':
+			// Explicit argument index, e.g. %[1]s
+			j := strings.IndexByte(format[i:], ']')
+			if j < 0 {
+				return -1
+			}
+			i += j
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			return i
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+// isValuePosition guesses whether an unquoted verb following prefix stands
+// for a value (e.g. after "=" or "IN (") rather than for an identifier.
+func isValuePosition(prefix string, params []model.TemplateParam) bool {
+	prefix = strings.TrimRight(prefix, " \t\r\n")
+	if prefix == "" {
+		return false
+	}
+
+	switch last := prefix[len(prefix)-1]; {
+	case strings.IndexByte("=<>+-*/", last) >= 0:
+		return true
+	case last == ',':
+		// Lists keep the kind of their previous element
+		return len(params) > 0 && params[len(params)-1].Kind != model.TemplateIdentifier
+	case last == '(':
+		return valueKeywords[lastWord(prefix[:len(prefix)-1])]
+	}
+
+	return valueKeywords[lastWord(prefix)]
+}
+
+// lastWord returns the trailing keyword of s in upper case
+func lastWord(s string) string {
+	s = strings.TrimRight(s, " \t\r\n")
+	i := len(s)
+	for i > 0 {
+		c := s[i-1]
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			break
+		}
+		i--
+	}
+	return strings.ToUpper(s[i:])
+}
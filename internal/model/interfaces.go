@@ -19,6 +19,14 @@ type Rule interface {
 	Check(segment *SQLSegment, node ast.StmtNode, schema *SchemaCtx) ([]Issue, error)
 }
 
+// RuleDescriber is optionally implemented by rules to document themselves
+// in reports that carry a rule catalogue (e.g. SARIF)
+type RuleDescriber interface {
+	// Description is a one-line summary of what the rule detects
+	Description() string
+	// DefaultLevel is the level of the rule's issues unless overridden
+	DefaultLevel() RiskLevel
+}
 
 // Reporter defines how to output results
 type Reporter interface {
@@ -1,22 +1,168 @@
 package model
 
-import "fmt"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
 
 // Location represents the physical location of a code segment
 type Location struct {
-	FilePath string
-	Line     int
+	FilePath  string
+	Line      int
+	Column    int // 1-based byte column of the first character, 0 if unknown
+	EndLine   int // Last line of multi-line segments, 0 if unknown
+	EndColumn int // Column just past the last character, 0 if unknown
 }
 
 func (l Location) String() string {
+	if l.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", l.FilePath, l.Line, l.Column)
+	}
 	return fmt.Sprintf("%s:%d", l.FilePath, l.Line)
 }
 
+// LastLine returns the last line of the location, Line if EndLine is not set
+func (l Location) LastLine() int {
+	if l.EndLine > l.Line {
+		return l.EndLine
+	}
+	return l.Line
+}
+
+// RelPath returns path relative to root with forward slashes. root may be a
+// file, in which case its directory is used. ok is false if the path is not
+// below root, in which case it is returned unchanged.
+func RelPath(root, path string) (string, bool) {
+	if root == "" {
+		return filepath.ToSlash(path), !filepath.IsAbs(path)
+	}
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		root = filepath.Dir(root)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return path, false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path, false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path, false
+	}
+	return filepath.ToSlash(rel), true
+}
+
 // SQLSegment represents an extracted SQL statement from source code
 type SQLSegment struct {
 	SQL      string
 	Location Location
 	Language string // e.g., "go", "python", "cpp"
+
+	// Confidence (0..1) that SQL is a real statement and not a string that
+	// merely starts with a SQL keyword. Zero means it was not estimated.
+	Confidence float64
+
+	// Templated is set when SQL was built from a printf-style format string.
+	// The verbs were replaced by typed placeholder values listed in TemplateParams.
+	Templated      bool
+	TemplateParams []TemplateParam
+
+	// Suppressions are the "sql-check:ignore" comments found next to the SQL
+	Suppressions []Suppression
+
+	// SourceMap maps SQL back to the source when it was assembled from
+	// several pieces (concatenations, constants, templates). Spans are sorted
+	// by offset. If empty, SQL is a verbatim copy of the source text starting
+	// at Location.
+	SourceMap []SourceSpan
+}
+
+// SourceSpan maps the SQL bytes from Offset up to the next span to the
+// source text starting at Line:Column. If Verbatim, those bytes are an
+// exact copy of the source, so positions inside the span are exact;
+// otherwise (escaped strings, constants, template values) they all map to
+// Line:Column.
+type SourceSpan struct {
+	Offset   int
+	Line     int
+	Column   int
+	Verbatim bool
+}
+
+// Position returns the source line and column of the byte at offset in SQL
+func (s *SQLSegment) Position(offset int) (line, column int) {
+	span := SourceSpan{Line: s.Location.Line, Column: s.Location.Column, Verbatim: true}
+	for _, sp := range s.SourceMap {
+		if sp.Offset > offset {
+			break
+		}
+		span = sp
+	}
+	if span.Column == 0 {
+		span.Column = 1
+	}
+
+	line, column = span.Line, span.Column
+	if !span.Verbatim || offset > len(s.SQL) {
+		return line, column
+	}
+	for _, c := range []byte(s.SQL[span.Offset:offset]) {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+// Placeholder is a bind parameter of a query
+type Placeholder struct {
+	Name   string // As written in the source, e.g. "?", "$1", ":user_id" or "@p1"
+	Offset int    // Byte offset in SQLSegment.SQL
+}
+
+// Suppression is an inline "sql-check:ignore" comment silencing findings
+type Suppression struct {
+	Types  []string // Issue types or rule names to ignore, empty means all
+	Reason string
+	Line   int // Line of the comment
+}
+
+// Matches reports whether the suppression applies to issue
+func (s Suppression) Matches(issue Issue) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if strings.EqualFold(t, issue.Type) || strings.EqualFold(t, issue.Rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateKind describes how a printf verb was substituted
+type TemplateKind string
+
+const (
+	TemplateIdentifier TemplateKind = "IDENTIFIER" // table/column name interpolation
+	TemplateString     TemplateKind = "STRING"
+	TemplateNumber     TemplateKind = "NUMBER"
+)
+
+// TemplateParam is a printf verb of a templated query
+type TemplateParam struct {
+	Verb   string // Original verb, e.g. "%s" or "%d"
+	Kind   TemplateKind
+	Offset int // Byte offset of the substituted value in SQLSegment.SQL
 }
 
 // RiskLevel defines the severity of an audit finding
@@ -28,6 +174,55 @@ const (
 	RiskLevelSuggestion RiskLevel = "SUGGESTION"
 )
 
+// Severity orders risk levels: higher is more severe, 0 for unknown levels
+func (l RiskLevel) Severity() int {
+	switch l {
+	case RiskLevelFatal:
+		return 3
+	case RiskLevelWarning:
+		return 2
+	case RiskLevelSuggestion:
+		return 1
+	}
+	return 0
+}
+
+// AtLeast reports whether l is as severe as threshold or more
+func (l RiskLevel) AtLeast(threshold RiskLevel) bool {
+	return l.Severity() >= threshold.Severity()
+}
+
+// Escalated returns the next more severe level, FATAL stays FATAL
+func (l RiskLevel) Escalated() RiskLevel {
+	switch l {
+	case RiskLevelSuggestion:
+		return RiskLevelWarning
+	case RiskLevelWarning:
+		return RiskLevelFatal
+	}
+	return l
+}
+
+// Downgraded returns the next less severe level, SUGGESTION stays SUGGESTION
+func (l RiskLevel) Downgraded() RiskLevel {
+	switch l {
+	case RiskLevelFatal:
+		return RiskLevelWarning
+	case RiskLevelWarning:
+		return RiskLevelSuggestion
+	}
+	return l
+}
+
+// ParseRiskLevel converts a case-insensitive level name into a RiskLevel
+func ParseRiskLevel(s string) (RiskLevel, error) {
+	switch level := RiskLevel(strings.ToUpper(strings.TrimSpace(s))); level {
+	case RiskLevelFatal, RiskLevelWarning, RiskLevelSuggestion:
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown risk level %q (expected fatal, warning or suggestion)", s)
+}
+
 // Issue represents a potential problem found by the auditor
 type Issue struct {
 	Type        string    // e.g., "NO_WHERE_CLAUSE", "INDEX_MISSING"
@@ -35,26 +230,280 @@ type Issue struct {
 	Message     string
 	Suggestion  string
 	Segment     SQLSegment
+	Rule        string // Name() of the rule that reported the issue
+
+	// Suppressed is set when an inline comment silences the issue.
+	// Suppressed issues are kept so that reports can count them.
+	Suppressed     bool
+	SuppressReason string
+
+	// Statement the issue was found in, for segments holding several statements
+	StatementIndex  int // Position of the statement in the segment, starting at 0
+	StatementOffset int // Byte offset of the statement inside Segment.SQL
+
+	// Offset is the byte offset in Segment.SQL of the expression the issue
+	// is about, e.g. the offending comparison. Zero means the whole statement.
+	Offset int
+
+	// Table is the table the issue is about, nil if it concerns the whole
+	// statement. Size-aware levels follow its estimated rows.
+	Table *Table
+	// Index is set when the rows the issue is about are those matching one
+	// key of this index, such as rows sorted after an index lookup. With a
+	// known cardinality, size-aware levels follow the rows per key.
+	Index *Index
+}
+
+// Location returns where the issue is in the source: the expression at
+// Offset, or else its statement up to the end of the segment.
+func (i Issue) Location() Location {
+	loc := Location{FilePath: i.Segment.Location.FilePath}
+	if i.Offset > 0 {
+		loc.Line, loc.Column = i.Segment.Position(i.Offset)
+		return loc
+	}
+
+	loc.Line, loc.Column = i.Segment.Position(i.StatementOffset)
+	loc.EndLine, loc.EndColumn = i.Segment.Location.EndLine, i.Segment.Location.EndColumn
+	return loc
 }
 
 // SchemaCtx represents the loaded database schema context
 type SchemaCtx struct {
+	// Tables are keyed by "database.table", or "table" for tables loaded
+	// without a database. Use Lookup to find a table by name.
 	Tables map[string]*Table
+
+	// LowerCaseTableNames follows MySQL's lower_case_table_names: with 0,
+	// database and table names are case-sensitive; with 1 they are stored
+	// in lower case and with 2 as given, both comparing case-insensitively.
+	LowerCaseTableNames int
+	// DefaultDatabase resolves unqualified table names when several
+	// databases have a table of that name
+	DefaultDatabase string
+}
+
+// NewSchemaCtx returns an empty schema
+func NewSchemaCtx(lowerCaseTableNames int) *SchemaCtx {
+	return &SchemaCtx{
+		Tables:              make(map[string]*Table),
+		LowerCaseTableNames: lowerCaseTableNames,
+	}
+}
+
+// key returns the key of a table in Tables
+func (s *SchemaCtx) key(database, name string) string {
+	if s.LowerCaseTableNames != 0 {
+		database, name = strings.ToLower(database), strings.ToLower(name)
+	}
+	if database == "" {
+		return name
+	}
+	return database + "." + name
+}
+
+func (s *SchemaCtx) sameName(a, b string) bool {
+	if s.LowerCaseTableNames != 0 {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// AddTable adds a table, replacing any table of the same database and name
+func (s *SchemaCtx) AddTable(t *Table) {
+	if s.LowerCaseTableNames == 1 {
+		t.Schema, t.Name = strings.ToLower(t.Schema), strings.ToLower(t.Name)
+	}
+	if s.Tables == nil {
+		s.Tables = make(map[string]*Table)
+	}
+	s.Tables[s.key(t.Schema, t.Name)] = t
+}
+
+// RemoveTable removes a table from the schema
+func (s *SchemaCtx) RemoveTable(t *Table) {
+	for key, table := range s.Tables {
+		if table == t {
+			delete(s.Tables, key)
+		}
+	}
+}
+
+// Lookup finds the table a query refers to as database.name, or just name
+// when database is empty. Tables loaded without a database match any
+// database. An unqualified name matches a table without a database or in
+// DefaultDatabase, else the table of that name if only one database has
+// it. Lookup is safe on a nil schema.
+func (s *SchemaCtx) Lookup(database, name string) (*Table, bool) {
+	if s == nil {
+		return nil, false
+	}
+	if t, ok := s.Tables[s.key(database, name)]; ok {
+		return t, true
+	}
+
+	// Candidates by preference; several at the best rank are ambiguous
+	var best *Table
+	bestRank, ties := 0, 0
+	for key, t := range s.Tables {
+		tableName := t.Name
+		if tableName == "" {
+			tableName = key
+		}
+		if !s.sameName(tableName, name) {
+			continue
+		}
+
+		rank := 1
+		switch {
+		case database != "" && t.Schema != "" && !s.sameName(t.Schema, database):
+			continue
+		case database != "" && t.Schema != "":
+			rank = 2
+		case database == "" && (t.Schema == "" || s.sameName(t.Schema, s.DefaultDatabase)):
+			rank = 2
+		}
+
+		switch {
+		case rank > bestRank:
+			best, bestRank, ties = t, rank, 1
+		case rank == bestRank:
+			ties++
+		}
+	}
+	if best == nil || ties > 1 {
+		return nil, false
+	}
+	return best, true
 }
 
 type Table struct {
+	Schema  string // Database of the table, empty if loaded without one
 	Name    string
 	Columns map[string]*Column
 	Indexes []*Index
+
+	// Rows is the estimated number of rows from the statistics file, 0 if unknown
+	Rows int64
 }
 
 type Column struct {
 	Name string
-	Type string // Simplified type representation
+	Type string // Declared type as a string, e.g. "varchar(255)"
+
+	BaseType      string  // Lower-case type name without length or attributes, e.g. "varchar", "int"
+	Length        int     // Declared length or display width, 0 if not given
+	Decimals      int     // Declared fractional digits (DECIMAL, DOUBLE, DATETIME), 0 if not given
+	Unsigned      bool
+	Nullable      bool
+	Default       *string // DEFAULT as SQL text (e.g. "'active'", "NULL"), nil if the column has none
+	Charset       string  // Empty when inherited from the table
+	Collation     string  // Empty when inherited from the table or charset
+	AutoIncrement bool
+
+	// Generated is the expression of a generated column, empty otherwise
+	Generated       string
+	GeneratedStored bool
+}
+
+// baseType returns BaseType, or the type name at the start of Type for
+// columns built without structured type info
+func (c *Column) baseType() string {
+	if c.BaseType != "" {
+		return c.BaseType
+	}
+	t := strings.ToLower(strings.TrimSpace(c.Type))
+	if i := strings.IndexAny(t, "( "); i >= 0 {
+		t = t[:i]
+	}
+	return t
+}
+
+// IsString reports whether the column holds character data
+func (c *Column) IsString() bool {
+	switch c.baseType() {
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set":
+		return true
+	}
+	return false
+}
+
+// IsNumeric reports whether the column holds numbers
+func (c *Column) IsNumeric() bool {
+	switch c.baseType() {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "bit", "bool", "boolean",
+		"decimal", "numeric", "float", "double", "real":
+		return true
+	}
+	return false
+}
+
+// IsTemporal reports whether the column holds dates or times
+func (c *Column) IsTemporal() bool {
+	switch c.baseType() {
+	case "date", "datetime", "timestamp", "time", "year":
+		return true
+	}
+	return false
 }
 
 type Index struct {
 	Name    string
-	Columns []string // Ordered list of column names in the index
+	// Columns is the ordered list of column names in the index. Key parts
+	// of functional indexes are expressions in parentheses, as written in
+	// the DDL, e.g. "(LOWER(`email`))".
+	Columns []string
 	Unique  bool
+	// Descending flags the key parts declared DESC, by position. nil if all
+	// are ascending.
+	Descending []bool
+
+	// Cardinality is the estimated number of distinct keys, 0 if unknown
+	Cardinality int64
+}
+
+// IsDescending reports whether key part i is stored in descending order
+func (idx *Index) IsDescending(i int) bool {
+	return i < len(idx.Descending) && idx.Descending[i]
+}
+
+// IsIndexExpression reports whether a key part of Index.Columns is an
+// expression rather than a column
+func IsIndexExpression(keyPart string) bool {
+	return strings.HasPrefix(keyPart, "(")
+}
+
+// Fingerprint identifies an issue independently of its line number, so it
+// survives code moving around. It hashes the issue type, the file path and
+// the whitespace-normalised SQL of the statement.
+func (i Issue) Fingerprint() string {
+	sql := i.Segment.SQL
+	if i.StatementOffset > 0 && i.StatementOffset < len(sql) {
+		sql = sql[i.StatementOffset:]
+	}
+	sql = strings.ToLower(strings.Join(strings.Fields(sql), " "))
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s", i.Type, filepath.ToSlash(filepath.Clean(i.Segment.Location.FilePath)), i.StatementIndex, sql)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// RelativeTo returns a copy of the issue whose file path is relative to
+// root (see RelPath), so that its location and fingerprint do not depend
+// on where the code is checked out
+func (i Issue) RelativeTo(root string) Issue {
+	i.Segment.Location.FilePath, _ = RelPath(root, i.Segment.Location.FilePath)
+	return i
+}
+
+// SplitSuppressed separates active issues from suppressed ones
+func SplitSuppressed(issues []Issue) (active, suppressed []Issue) {
+	for _, issue := range issues {
+		if issue.Suppressed {
+			suppressed = append(suppressed, issue)
+		} else {
+			active = append(active, issue)
+		}
+	}
+	return active, suppressed
 }
@@ -0,0 +1,478 @@
+package parser
+
+import (
+	"strings"
+
+	"sql-check/internal/model"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/test_driver"
+)
+
+// Binding resolves the table and column references of a statement: table
+// aliases, qualified columns, derived tables and CTEs.
+type Binding struct {
+	scopes  map[ast.Node]*Scope
+	columns map[*ast.ColumnNameExpr]*BoundColumn
+	// placeholders are the rewritten bind parameters by offset
+	placeholders map[int]string
+	// start and end are the byte range of the statement, if known
+	start, end int
+	spanned    bool
+}
+
+// Scope holds the table sources of a query block (a SELECT, UPDATE or
+// DELETE) as they can be referenced by its columns
+type Scope struct {
+	Tables []*TableRef // In FROM order
+	Parent *Scope      // Enclosing query block, nil for the statement
+}
+
+// TableRef is a table source of a query block
+type TableRef struct {
+	Name     string // Alias, or the table name if there is none
+	Database string // Database qualifier as written, empty if unqualified
+
+	// Table is the base table in the schema, nil for derived tables, CTEs
+	// and tables the schema does not know
+	Table *model.Table
+	// Node is the table name as written, nil for subqueries in FROM
+	Node *ast.TableName
+	// Source is the entry of the FROM clause (or UPDATE/DELETE table list)
+	Source *ast.TableSource
+
+	// Derived is set for subqueries in FROM and CTEs. Their output columns
+	// are in Columns, keyed by lower-case name, with the definition of the
+	// underlying column if it is a plain one.
+	Derived bool
+	Columns map[string]*model.Column
+}
+
+// BoundColumn is a column reference resolved to its table source
+type BoundColumn struct {
+	Ref  *TableRef
+	Name string
+	// Column is the definition, nil if unknown (table not in the schema or
+	// computed column of a derived table)
+	Column *model.Column
+}
+
+// Bind resolves the references of a statement against schema, which may be nil
+func Bind(node ast.StmtNode, schema *model.SchemaCtx) *Binding {
+	b := &binder{
+		schema: schema,
+		binding: &Binding{
+			scopes:  make(map[ast.Node]*Scope),
+			columns: make(map[*ast.ColumnNameExpr]*BoundColumn),
+		},
+	}
+	node.Accept(b)
+	return b.binding
+}
+
+// BindStatement is Bind for a statement of ParseAll, which also maps its '?'
+// markers back to the bind parameters as written
+func BindStatement(stmt Statement, schema *model.SchemaCtx) *Binding {
+	binding := Bind(stmt.Node, schema)
+	binding.start, binding.end, binding.spanned = stmt.Offset, stmt.End, true
+	binding.placeholders = make(map[int]string, len(stmt.Placeholders))
+	for _, p := range stmt.Placeholders {
+		binding.placeholders[p.Offset] = p.Name
+	}
+	return binding
+}
+
+// Span returns the byte range of the statement in the SQL given to
+// ParseAll, false if the binding was not made by BindStatement
+func (b *Binding) Span() (start, end int, ok bool) {
+	if b == nil || !b.spanned {
+		return 0, 0, false
+	}
+	return b.start, b.end, true
+}
+
+// Placeholder returns the bind parameter a '?' marker stands for, as
+// written in the SQL: "$1", ":user_id", "@p1" or "?"
+func (b *Binding) Placeholder(marker *test_driver.ParamMarkerExpr) string {
+	if b != nil {
+		if name, ok := b.placeholders[marker.Offset]; ok {
+			return name
+		}
+	}
+	return "?"
+}
+
+// Column returns what a column reference resolves to, nil if it does not
+// resolve to a single table source
+func (b *Binding) Column(expr *ast.ColumnNameExpr) *BoundColumn {
+	if b == nil {
+		return nil
+	}
+	return b.columns[expr]
+}
+
+// Scope returns the scope of a SELECT, UPDATE or DELETE, nil for other nodes
+func (b *Binding) Scope(block ast.Node) *Scope {
+	if b == nil {
+		return nil
+	}
+	return b.scopes[block]
+}
+
+// Lookup finds the table source a qualifier (alias or table name) refers
+// to, in this scope only
+func (s *Scope) Lookup(database, name string) *TableRef {
+	for _, ref := range s.Tables {
+		if !strings.EqualFold(ref.Name, name) {
+			continue
+		}
+		if database == "" || strings.EqualFold(ref.Database, database) ||
+			(ref.Table != nil && strings.EqualFold(ref.Table.Schema, database)) {
+			return ref
+		}
+	}
+	return nil
+}
+
+// BaseTables returns the table sources that are tables of the schema
+func (s *Scope) BaseTables() []*TableRef {
+	var refs []*TableRef
+	for _, ref := range s.Tables {
+		if ref.Table != nil {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// column returns the definition of a column of the table source and
+// whether the source has it. For unknown tables it is never found.
+func (ref *TableRef) column(name string) (*model.Column, bool) {
+	if ref.Derived {
+		col, ok := ref.Columns[strings.ToLower(name)]
+		return col, ok
+	}
+	if ref.Table == nil {
+		return nil, false
+	}
+	col := FindColumn(ref.Table, name)
+	return col, col != nil
+}
+
+// FindColumn returns a column of a table by name, compared
+// case-insensitively as MySQL does, nil if there is none
+func FindColumn(t *model.Table, name string) *model.Column {
+	if col, ok := t.Columns[name]; ok {
+		return col
+	}
+	for colName, col := range t.Columns {
+		if strings.EqualFold(colName, name) {
+			return col
+		}
+	}
+	return nil
+}
+
+// binder walks a statement, building a scope per query block and binding
+// the columns of each block to it
+type binder struct {
+	schema  *model.SchemaCtx
+	binding *Binding
+
+	scopes []*Scope                       // Query blocks being walked, innermost last
+	ctes   [][]*ast.CommonTableExpression // WITH clauses in effect, innermost last
+	// resolving guards against CTEs whose columns refer to themselves
+	resolving map[*ast.CommonTableExpression]bool
+}
+
+func (b *binder) Enter(in ast.Node) (ast.Node, bool) {
+	switch n := in.(type) {
+	case *ast.SelectStmt:
+		b.pushWith(n.With)
+		b.scopes = append(b.scopes, b.scopeOf(n))
+	case *ast.UpdateStmt:
+		b.pushWith(n.With)
+		b.scopes = append(b.scopes, b.scopeOf(n))
+	case *ast.DeleteStmt:
+		b.pushWith(n.With)
+		b.scopes = append(b.scopes, b.scopeOf(n))
+	case *ast.SetOprStmt:
+		b.pushWith(n.With)
+	case *ast.ColumnNameExpr:
+		if len(b.scopes) > 0 {
+			if bc := b.resolve(b.scopes[len(b.scopes)-1], n.Name); bc != nil {
+				b.binding.columns[n] = bc
+			}
+		}
+	}
+	return in, false
+}
+
+func (b *binder) Leave(in ast.Node) (ast.Node, bool) {
+	switch n := in.(type) {
+	case *ast.SelectStmt:
+		b.scopes = b.scopes[:len(b.scopes)-1]
+		b.popWith(n.With)
+	case *ast.UpdateStmt:
+		b.scopes = b.scopes[:len(b.scopes)-1]
+		b.popWith(n.With)
+	case *ast.DeleteStmt:
+		b.scopes = b.scopes[:len(b.scopes)-1]
+		b.popWith(n.With)
+	case *ast.SetOprStmt:
+		b.popWith(n.With)
+	}
+	return in, true
+}
+
+func (b *binder) pushWith(with *ast.WithClause) {
+	if with != nil {
+		b.ctes = append(b.ctes, with.CTEs)
+	}
+}
+
+func (b *binder) popWith(with *ast.WithClause) {
+	if with != nil {
+		b.ctes = b.ctes[:len(b.ctes)-1]
+	}
+}
+
+// scopeOf returns the scope of a query block, building it on first use
+func (b *binder) scopeOf(block ast.Node) *Scope {
+	if scope, ok := b.binding.scopes[block]; ok {
+		return scope
+	}
+
+	scope := &Scope{}
+	if len(b.scopes) > 0 {
+		scope.Parent = b.scopes[len(b.scopes)-1]
+	}
+	b.binding.scopes[block] = scope
+
+	var from *ast.TableRefsClause
+	switch n := block.(type) {
+	case *ast.SelectStmt:
+		from = n.From
+	case *ast.UpdateStmt:
+		from = n.TableRefs
+	case *ast.DeleteStmt:
+		from = n.TableRefs
+	}
+	if from != nil {
+		b.addSources(scope, from.TableRefs)
+	}
+	return scope
+}
+
+// addSources adds the table sources of a FROM clause to scope
+func (b *binder) addSources(scope *Scope, node ast.ResultSetNode) {
+	switch n := node.(type) {
+	case *ast.Join:
+		if n.Left != nil {
+			b.addSources(scope, n.Left)
+		}
+		if n.Right != nil {
+			b.addSources(scope, n.Right)
+		}
+	case *ast.TableSource:
+		switch src := n.Source.(type) {
+		case *ast.TableName:
+			ref := b.tableRef(src)
+			ref.Source = n
+			if n.AsName.O != "" {
+				ref.Name = n.AsName.O
+			}
+			scope.Tables = append(scope.Tables, ref)
+		case *ast.SelectStmt, *ast.SetOprStmt:
+			scope.Tables = append(scope.Tables, &TableRef{
+				Name:    n.AsName.O,
+				Source:  n,
+				Derived: true,
+				Columns: b.outputColumns(src, scope),
+			})
+		case *ast.Join:
+			b.addSources(scope, src)
+		}
+	}
+}
+
+// tableRef resolves a table name to a CTE in effect or a base table
+func (b *binder) tableRef(tn *ast.TableName) *TableRef {
+	ref := &TableRef{Name: tn.Name.O, Database: tn.Schema.O, Node: tn}
+	if tn.Schema.O == "" {
+		if cte := b.cte(tn.Name.O); cte != nil {
+			ref.Derived = true
+			ref.Columns = b.cteColumns(cte)
+			return ref
+		}
+	}
+	ref.Table, _ = b.schema.Lookup(tn.Schema.O, tn.Name.O)
+	return ref
+}
+
+// cte returns the innermost CTE of that name in effect
+func (b *binder) cte(name string) *ast.CommonTableExpression {
+	for i := len(b.ctes) - 1; i >= 0; i-- {
+		for _, cte := range b.ctes[i] {
+			if strings.EqualFold(cte.Name.O, name) {
+				return cte
+			}
+		}
+	}
+	return nil
+}
+
+func (b *binder) cteColumns(cte *ast.CommonTableExpression) map[string]*model.Column {
+	if b.resolving[cte] || cte.Query == nil {
+		return map[string]*model.Column{}
+	}
+	if b.resolving == nil {
+		b.resolving = make(map[*ast.CommonTableExpression]bool)
+	}
+	b.resolving[cte] = true
+	defer delete(b.resolving, cte)
+
+	outputs, ordered := b.outputs(cte.Query.Query, nil)
+	if len(cte.ColNameList) == 0 {
+		return columnMap(outputs)
+	}
+
+	// Explicit column names rename the output columns in order
+	cols := make(map[string]*model.Column, len(cte.ColNameList))
+	for i, name := range cte.ColNameList {
+		var def *model.Column
+		if ordered && i < len(outputs) {
+			def = outputs[i].def
+		}
+		cols[name.L] = def
+	}
+	return cols
+}
+
+// outputColumns returns the columns of a subquery by lower-case name. parent
+// is the scope the subquery appears in.
+func (b *binder) outputColumns(node ast.ResultSetNode, parent *Scope) map[string]*model.Column {
+	outputs, _ := b.outputs(node, parent)
+	return columnMap(outputs)
+}
+
+// output is a column of a subquery result
+type output struct {
+	name string // Lower case
+	def  *model.Column
+}
+
+func columnMap(outputs []output) map[string]*model.Column {
+	cols := make(map[string]*model.Column, len(outputs))
+	for _, o := range outputs {
+		cols[o.name] = o.def
+	}
+	return cols
+}
+
+// outputs returns the columns of a subquery. They are in select list order
+// unless a wildcard expanded a base table, whose column order is unknown.
+// parent is the scope the subquery appears in, nil to use the blocks being
+// walked.
+func (b *binder) outputs(node ast.ResultSetNode, parent *Scope) (outputs []output, ordered bool) {
+	sel := firstSelect(node)
+	if sel == nil || sel.Fields == nil {
+		return nil, false
+	}
+
+	if parent != nil {
+		b.scopes = append(b.scopes, parent)
+		defer func() { b.scopes = b.scopes[:len(b.scopes)-1] }()
+	}
+	if set, ok := node.(*ast.SetOprStmt); ok {
+		b.pushWith(set.With)
+		defer b.popWith(set.With)
+	}
+	b.pushWith(sel.With)
+	defer b.popWith(sel.With)
+	scope := b.scopeOf(sel)
+
+	ordered = true
+	for _, field := range sel.Fields.Fields {
+		if field.WildCard != nil {
+			for _, ref := range scope.Tables {
+				if field.WildCard.Table.O != "" && !strings.EqualFold(field.WildCard.Table.O, ref.Name) {
+					continue
+				}
+				cols := ref.Columns
+				if !ref.Derived && ref.Table != nil {
+					cols = ref.Table.Columns
+				}
+				for name, col := range cols {
+					outputs = append(outputs, output{name: strings.ToLower(name), def: col})
+				}
+				ordered = false
+			}
+			continue
+		}
+
+		name := field.AsName.L
+		var def *model.Column
+		if colExpr, ok := field.Expr.(*ast.ColumnNameExpr); ok {
+			if name == "" {
+				name = colExpr.Name.Name.L
+			}
+			if bc := b.resolve(scope, colExpr.Name); bc != nil {
+				def = bc.Column
+			}
+		}
+		// Computed fields without an alias stay unnamed: they keep their
+		// position but cannot be referenced by name
+		outputs = append(outputs, output{name: name, def: def})
+	}
+	return outputs, ordered
+}
+
+// firstSelect returns the SELECT that names the columns of a result set
+func firstSelect(node ast.Node) *ast.SelectStmt {
+	switch n := node.(type) {
+	case *ast.SelectStmt:
+		return n
+	case *ast.SetOprStmt:
+		if n.SelectList != nil && len(n.SelectList.Selects) > 0 {
+			return firstSelect(n.SelectList.Selects[0])
+		}
+	case *ast.SetOprSelectList:
+		if len(n.Selects) > 0 {
+			return firstSelect(n.Selects[0])
+		}
+	}
+	return nil
+}
+
+// resolve binds a column name in scope, then in the enclosing scopes
+func (b *binder) resolve(scope *Scope, name *ast.ColumnName) *BoundColumn {
+	for s := scope; s != nil; s = s.Parent {
+		if name.Table.O != "" {
+			if ref := s.Lookup(name.Schema.O, name.Table.O); ref != nil {
+				col, _ := ref.column(name.Name.O)
+				return &BoundColumn{Ref: ref, Name: name.Name.O, Column: col}
+			}
+			continue
+		}
+
+		var match *BoundColumn
+		found := 0
+		for _, ref := range s.Tables {
+			if col, ok := ref.column(name.Name.O); ok {
+				match = &BoundColumn{Ref: ref, Name: name.Name.O, Column: col}
+				found++
+			}
+		}
+		switch {
+		case found == 1:
+			return match
+		case found > 1:
+			return nil // Ambiguous
+		case len(s.Tables) == 1 && s.Tables[0].Table == nil && !s.Tables[0].Derived:
+			// The only table is not in the schema, it must be its column
+			return &BoundColumn{Ref: s.Tables[0], Name: name.Name.O}
+		}
+	}
+	return nil
+}
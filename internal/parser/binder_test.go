@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"testing"
+
+	"sql-check/internal/model"
+
+	"github.com/pingcap/tidb/parser/ast"
+)
+
+func bindingSchema() *model.SchemaCtx {
+	schema := model.NewSchemaCtx(1)
+	schema.AddTable(&model.Table{Name: "users", Columns: map[string]*model.Column{
+		"id":    {Name: "id", Type: "int"},
+		"email": {Name: "email", Type: "varchar(255)"},
+	}})
+	schema.AddTable(&model.Table{Name: "orders", Columns: map[string]*model.Column{
+		"id":      {Name: "id", Type: "bigint"},
+		"user_id": {Name: "user_id", Type: "int"},
+		"status":  {Name: "status", Type: "varchar(16)"},
+	}})
+	return schema
+}
+
+// boundColumns binds sql and returns, per column reference in the order
+// the AST is walked, "table.column" of what it resolves to ("?" if unresolved).
+// Derived tables and CTEs are named by their alias with a "~" prefix.
+func boundColumns(t *testing.T, sql string) []string {
+	t.Helper()
+	stmt, err := NewSQLParser().Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", sql, err)
+	}
+	binding := Bind(stmt, bindingSchema())
+
+	var got []string
+	stmt.Accept(&columnCollector{fn: func(expr *ast.ColumnNameExpr) {
+		bc := binding.Column(expr)
+		switch {
+		case bc == nil:
+			got = append(got, "?")
+		case bc.Ref.Derived:
+			got = append(got, "~"+bc.Ref.Name+"."+bc.Name)
+		case bc.Ref.Table != nil:
+			got = append(got, bc.Ref.Table.Name+"."+bc.Name)
+		default:
+			got = append(got, "!"+bc.Ref.Name+"."+bc.Name)
+		}
+	}})
+	return got
+}
+
+type columnCollector struct {
+	fn func(expr *ast.ColumnNameExpr)
+}
+
+func (v *columnCollector) Enter(in ast.Node) (ast.Node, bool) {
+	if expr, ok := in.(*ast.ColumnNameExpr); ok {
+		v.fn(expr)
+	}
+	return in, false
+}
+
+func (v *columnCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "aliases in a join",
+			sql:  "SELECT o.id FROM orders o JOIN users u ON u.id = o.user_id WHERE u.email = ?",
+			want: []string{"orders.id", "users.id", "orders.user_id", "users.email"},
+		},
+		{
+			name: "unqualified columns by the table that has them",
+			sql:  "SELECT status FROM orders JOIN Users ON users.id = user_id WHERE email = ? AND id = 1",
+			want: []string{"orders.status", "users.id", "orders.user_id", "users.email", "?"},
+		},
+		{
+			name: "self join",
+			sql:  "SELECT a.id FROM users a JOIN users b ON a.email = b.email",
+			want: []string{"users.id", "users.email", "users.email"},
+		},
+		{
+			name: "derived table",
+			sql:  "SELECT d.mail FROM (SELECT email AS mail FROM users) d WHERE d.mail = ?",
+			want: []string{"~d.mail", "users.email", "~d.mail"},
+		},
+		{
+			name: "CTE",
+			sql:  "WITH recent AS (SELECT user_id FROM orders) SELECT u.email FROM users u JOIN recent r ON r.user_id = u.id",
+			want: []string{"orders.user_id", "users.email", "~r.user_id", "users.id"},
+		},
+		{
+			name: "correlated subquery",
+			sql:  "SELECT id FROM users u WHERE EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id AND status = 'paid')",
+			want: []string{"users.id", "orders.user_id", "users.id", "orders.status"},
+		},
+		{
+			name: "table not in the schema",
+			sql:  "SELECT name FROM customers WHERE id = 1",
+			want: []string{"!customers.name", "!customers.id"},
+		},
+		{
+			name: "UPDATE with join",
+			sql:  "UPDATE orders o JOIN users u ON u.id = o.user_id SET o.status = 'x' WHERE u.email = ?",
+			want: []string{"users.id", "orders.user_id", "users.email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := boundColumns(t, tt.sql)
+			if len(got) != len(tt.want) {
+				t.Fatalf("bound %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("bound %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBind_DerivedColumnDefinition(t *testing.T) {
+	sql := "SELECT * FROM (SELECT email AS mail FROM users) d WHERE d.mail = 1"
+	stmt, err := NewSQLParser().Parse(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binding := Bind(stmt, bindingSchema())
+
+	where := stmt.(*ast.SelectStmt).Where.(*ast.BinaryOperationExpr)
+	bc := binding.Column(where.L.(*ast.ColumnNameExpr))
+	if bc == nil || bc.Column == nil || bc.Column.Name != "email" {
+		t.Errorf("d.mail should carry the definition of users.email, got %+v", bc)
+	}
+
+	scope := binding.Scope(stmt)
+	if len(scope.Tables) != 1 || !scope.Tables[0].Derived || len(scope.BaseTables()) != 0 {
+		t.Errorf("Unexpected scope %+v", scope.Tables)
+	}
+}
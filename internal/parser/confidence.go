@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	dmlStart = regexp.MustCompile(`(?is)^\s*(SELECT|INSERT|UPDATE|DELETE)\b`)
+
+	// Clauses that are expected to follow each statement keyword
+	dmlBody = map[string]*regexp.Regexp{
+		"SELECT": regexp.MustCompile(`(?is)\bFROM\b`),
+		"INSERT": regexp.MustCompile(`(?is)\bINTO\b|\bVALUES?\b`),
+		"UPDATE": regexp.MustCompile(`(?is)\bSET\b`),
+		"DELETE": regexp.MustCompile(`(?is)\bFROM\b`),
+	}
+
+	sqlClauses = regexp.MustCompile(`(?is)\b(WHERE|JOIN|GROUP\s+BY|ORDER\s+BY|LIMIT|HAVING|VALUES)\b`)
+	sqlSymbols = regexp.MustCompile("[=?(),`*]|\\$\\d|:\\w")
+
+	// Words that are common in prose but rare in SQL
+	proseWords = regexp.MustCompile(`(?i)\b(the|you|your|are|was|please|this|that|will)\b`)
+)
+
+// Confidence estimates how likely s is a real SQL statement rather than
+// an ordinary string that happens to start with a SQL keyword
+// (e.g. "SELECTING items is fun" or "Select a file"). It returns a value
+// between 0 and 1.
+func Confidence(s string) float64 {
+	m := dmlStart.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+
+	score := 0.4
+	if dmlBody[strings.ToUpper(m[1])].MatchString(s) {
+		score += 0.3
+	}
+	if sqlClauses.MatchString(s) {
+		score += 0.2
+	}
+	if sqlSymbols.MatchString(s) {
+		score += 0.1
+	}
+
+	if n := len(proseWords.FindAllString(s, -1)); n > 0 {
+		score -= 0.2 * float64(n)
+	}
+	if t := strings.TrimSpace(s); strings.HasSuffix(t, ".") || strings.HasSuffix(t, "!") {
+		score -= 0.2
+	}
+
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseError describes a SQL syntax error and where it occurred in the SQL text
+type ParseError struct {
+	Line   int // 1-based line inside the SQL text
+	Column int // 1-based column inside the SQL text
+	Offset int // Byte offset inside the SQL text
+	Near   string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// TiDB reports errors as: line L column C near "<rest of input>"<msg> (total length N)
+var (
+	tidbErrPos    = regexp.MustCompile(`(?s)^line (\d+) column (\d+) near "(.*)"`)
+	tidbErrLength = regexp.MustCompile(`\(total length (\d+)\)\s*$`)
+)
+
+// newParseError converts a TiDB parser error into a ParseError.
+// The position is derived from the remaining input the parser stopped at.
+func newParseError(sql string, err error) *ParseError {
+	pe := &ParseError{Line: 1, Column: 1, Err: err}
+
+	m := tidbErrPos.FindStringSubmatch(err.Error())
+	if m == nil {
+		return pe
+	}
+	pe.Line, _ = strconv.Atoi(m[1])
+	pe.Column, _ = strconv.Atoi(m[2])
+	pe.Near = m[3]
+
+	// The "near" text is the unparsed tail of the input, possibly truncated
+	remaining := len(pe.Near)
+	if lm := tidbErrLength.FindStringSubmatch(err.Error()); lm != nil {
+		remaining, _ = strconv.Atoi(lm[1])
+	} else if !strings.HasSuffix(sql, pe.Near) {
+		return pe
+	}
+	if remaining > len(sql) {
+		return pe
+	}
+
+	pe.Offset = len(sql) - remaining
+	pe.Line, pe.Column = lineCol(sql, pe.Offset)
+	return pe
+}
+
+// AsParseError extracts a ParseError from err if there is one
+func AsParseError(err error) (*ParseError, bool) {
+	var pe *ParseError
+	ok := errors.As(err, &pe)
+	return pe, ok
+}
+
+// lineCol converts a byte offset into a 1-based line and column
+func lineCol(s string, offset int) (int, int) {
+	if offset > len(s) {
+		offset = len(s)
+	}
+	line := 1 + strings.Count(s[:offset], "\n")
+	col := offset + 1
+	if i := strings.LastIndexByte(s[:offset], '\n'); i >= 0 {
+		col = offset - i
+	}
+	return line, col
+}
@@ -0,0 +1,387 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sql-check/internal/model"
+
+	"github.com/pingcap/tidb/parser/ast"
+)
+
+// Migration file naming conventions
+var (
+	// golang-migrate: 000001_create_users.up.sql / .down.sql
+	golangMigrateFile = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+	// Flyway versioned (V1__init.sql, V1.2__add_index.sql), undo (U1__...)
+	// and repeatable (R__views.sql) migrations
+	flywayFile = regexp.MustCompile(`^([VUR])([0-9._]*)__.*\.sql$`)
+)
+
+// migration is a schema file of a migrations directory
+type migration struct {
+	path    string
+	rank    int   // Versioned migrations first, then repeatable ones, then other files
+	version []int // Version parts, compared numerically
+}
+
+// Ranks of migration files, in replay order
+const (
+	rankVersioned = iota
+	rankRepeatable
+	rankPlain
+)
+
+// MigrationFiles lists the .sql files of a directory in the order they are
+// applied. golang-migrate and Flyway versions are compared numerically,
+// down and undo migrations are left out. Other .sql files come last, by name.
+func MigrationFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(name), ".sql") {
+			continue
+		}
+
+		m := migration{path: filepath.Join(dir, name), rank: rankPlain}
+		if match := golangMigrateFile.FindStringSubmatch(name); match != nil {
+			if match[2] == "down" {
+				continue
+			}
+			m.rank, m.version = rankVersioned, parseVersion(match[1])
+		} else if match := flywayFile.FindStringSubmatch(name); match != nil {
+			switch match[1] {
+			case "U":
+				continue
+			case "V":
+				m.rank, m.version = rankVersioned, parseVersion(match[2])
+			case "R":
+				m.rank = rankRepeatable
+			}
+		}
+		migrations = append(migrations, m)
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		a, b := migrations[i], migrations[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if c := compareVersions(a.version, b.version); c != 0 {
+			return c < 0
+		}
+		return a.path < b.path
+	})
+
+	files := make([]string, len(migrations))
+	for i, m := range migrations {
+		files[i] = m.path
+	}
+	return files, nil
+}
+
+// parseVersion splits a version like "1.2_3" into its numeric parts
+func parseVersion(s string) []int {
+	var parts []int
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '_' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// ddlReplay replays schema statements onto a schema
+type ddlReplay struct {
+	schema   *model.SchemaCtx
+	database string // Current database, set by USE
+}
+
+// table finds the table a statement refers to
+func (r *ddlReplay) table(tn *ast.TableName) (*model.Table, bool) {
+	return r.schema.Lookup(r.databaseOf(tn), tn.Name.O)
+}
+
+// databaseOf returns the database of a table name, the current one if unqualified
+func (r *ddlReplay) databaseOf(tn *ast.TableName) string {
+	if tn.Schema.O != "" {
+		return tn.Schema.O
+	}
+	return r.database
+}
+
+// apply replays a schema statement. Statements that do not change tables
+// or indexes, or refer to unknown tables, are ignored.
+func (r *ddlReplay) apply(stmt ast.StmtNode) {
+	schema := r.schema
+	switch s := stmt.(type) {
+	case *ast.UseStmt:
+		r.database = s.DBName
+
+	case *ast.DropDatabaseStmt:
+		for _, t := range schema.Tables {
+			if t.Schema != "" && strings.EqualFold(t.Schema, s.Name.O) {
+				schema.RemoveTable(t)
+			}
+		}
+
+	case *ast.CreateTableStmt:
+		if old, exists := r.table(s.Table); exists && s.IfNotExists && strings.EqualFold(old.Schema, r.databaseOf(s.Table)) {
+			return
+		}
+		var table *model.Table
+		if s.ReferTable != nil {
+			// CREATE TABLE ... LIKE copies the definition
+			src, ok := r.table(s.ReferTable)
+			if !ok {
+				return
+			}
+			table = copyTable(src)
+		} else {
+			table = parseCreateTable(s)
+		}
+		table.Schema, table.Name = r.databaseOf(s.Table), s.Table.Name.O
+		schema.AddTable(table)
+
+	case *ast.AlterTableStmt:
+		table, ok := r.table(s.Table)
+		if !ok {
+			return
+		}
+		for _, spec := range s.Specs {
+			r.alterTable(table, spec)
+		}
+
+	case *ast.CreateIndexStmt:
+		table, ok := r.table(s.Table)
+		if !ok {
+			return
+		}
+		if findIndex(table, s.IndexName) >= 0 {
+			return
+		}
+		table.Indexes = append(table.Indexes, &model.Index{
+			Name:       s.IndexName,
+			Unique:     s.KeyType == ast.IndexKeyTypeUnique,
+			Columns:    indexColumns(s.IndexPartSpecifications),
+			Descending: indexDescending(s.IndexPartSpecifications),
+		})
+
+	case *ast.DropIndexStmt:
+		if table, ok := r.table(s.Table); ok {
+			dropIndex(table, s.IndexName)
+		}
+
+	case *ast.RenameTableStmt:
+		for _, t := range s.TableToTables {
+			if table, ok := r.table(t.OldTable); ok {
+				r.renameTable(table, t.NewTable)
+			}
+		}
+
+	case *ast.DropTableStmt:
+		if s.IsView {
+			return
+		}
+		for _, tn := range s.Tables {
+			if table, ok := r.table(tn); ok {
+				schema.RemoveTable(table)
+			}
+		}
+	}
+}
+
+// renameTable renames a table, possibly moving it to another database
+func (r *ddlReplay) renameTable(table *model.Table, to *ast.TableName) {
+	r.schema.RemoveTable(table)
+	table.Schema, table.Name = r.databaseOf(to), to.Name.O
+	r.schema.AddTable(table)
+}
+
+// alterTable applies a single ALTER TABLE operation
+func (r *ddlReplay) alterTable(table *model.Table, spec *ast.AlterTableSpec) {
+	switch spec.Tp {
+	case ast.AlterTableAddColumns:
+		for _, col := range spec.NewColumns {
+			addColumn(table, col)
+		}
+		for _, cons := range spec.NewConstraints {
+			if idx := indexFromConstraint(cons); idx != nil {
+				addIndex(table, idx)
+			}
+		}
+
+	case ast.AlterTableDropColumn:
+		dropColumn(table, spec.OldColumnName.Name.O)
+
+	case ast.AlterTableModifyColumn:
+		if len(spec.NewColumns) > 0 {
+			// The new definition replaces the column under its own spelling
+			if col := FindColumn(table, spec.NewColumns[0].Name.Name.O); col != nil {
+				delete(table.Columns, col.Name)
+			}
+			dropInlineIndexes(table, spec.NewColumns[0])
+			addColumn(table, spec.NewColumns[0])
+		}
+
+	case ast.AlterTableChangeColumn:
+		if len(spec.NewColumns) > 0 {
+			oldName, newName := spec.OldColumnName.Name.O, spec.NewColumns[0].Name.Name.O
+			renameColumn(table, oldName, newName)
+			dropInlineIndexes(table, spec.NewColumns[0])
+			addColumn(table, spec.NewColumns[0])
+		}
+
+	case ast.AlterTableRenameColumn:
+		renameColumn(table, spec.OldColumnName.Name.O, spec.NewColumnName.Name.O)
+
+	case ast.AlterTableAddConstraint:
+		if idx := indexFromConstraint(spec.Constraint); idx != nil {
+			addIndex(table, idx)
+		}
+
+	case ast.AlterTableDropIndex:
+		dropIndex(table, spec.Name)
+
+	case ast.AlterTableDropPrimaryKey:
+		dropIndex(table, "PRIMARY")
+
+	case ast.AlterTableRenameIndex:
+		if i := findIndex(table, spec.FromKey.O); i >= 0 {
+			table.Indexes[i].Name = spec.ToKey.O
+		}
+
+	case ast.AlterTableRenameTable:
+		r.renameTable(table, spec.NewTable)
+	}
+}
+
+func findIndex(table *model.Table, name string) int {
+	for i, idx := range table.Indexes {
+		if strings.EqualFold(idx.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func dropIndex(table *model.Table, name string) {
+	if i := findIndex(table, name); i >= 0 {
+		table.Indexes = append(table.Indexes[:i], table.Indexes[i+1:]...)
+	}
+}
+
+// dropInlineIndexes removes the single-column indexes that the inline
+// PRIMARY KEY and UNIQUE options of a redefined column add again
+func dropInlineIndexes(table *model.Table, col *ast.ColumnDef) {
+	name := col.Name.Name.O
+	for _, opt := range col.Options {
+		switch opt.Tp {
+		case ast.ColumnOptionPrimaryKey:
+			if i := findIndex(table, "PRIMARY"); i >= 0 && isColumnIndex(table.Indexes[i], name) {
+				table.Indexes = append(table.Indexes[:i], table.Indexes[i+1:]...)
+			}
+		case ast.ColumnOptionUniqKey:
+			indexes := table.Indexes[:0]
+			for _, idx := range table.Indexes {
+				if idx.Name == "PRIMARY" || !idx.Unique || !isColumnIndex(idx, name) {
+					indexes = append(indexes, idx)
+				}
+			}
+			table.Indexes = indexes
+		}
+	}
+}
+
+// isColumnIndex reports whether an index is on the named column alone
+func isColumnIndex(idx *model.Index, name string) bool {
+	return len(idx.Columns) == 1 && strings.EqualFold(idx.Columns[0], name)
+}
+
+// dropColumn removes a column and its key parts. Indexes left without
+// columns are dropped, as MySQL does.
+func dropColumn(table *model.Table, name string) {
+	if col := FindColumn(table, name); col != nil {
+		delete(table.Columns, col.Name)
+	}
+
+	indexes := table.Indexes[:0]
+	for _, idx := range table.Indexes {
+		cols := idx.Columns[:0]
+		var desc []bool
+		for i, col := range idx.Columns {
+			if !strings.EqualFold(col, name) {
+				cols = append(cols, col)
+				desc = append(desc, idx.IsDescending(i))
+			}
+		}
+		idx.Columns = cols
+		if idx.Descending != nil {
+			idx.Descending = desc
+		}
+		if len(cols) > 0 {
+			indexes = append(indexes, idx)
+		}
+	}
+	table.Indexes = indexes
+}
+
+// renameColumn renames a column and its key parts
+func renameColumn(table *model.Table, oldName, newName string) {
+	if col := FindColumn(table, oldName); col != nil {
+		delete(table.Columns, col.Name)
+		col.Name = newName
+		table.Columns[newName] = col
+	}
+	for _, idx := range table.Indexes {
+		for i, col := range idx.Columns {
+			if strings.EqualFold(col, oldName) {
+				idx.Columns[i] = newName
+			}
+		}
+	}
+}
+
+// copyTable returns a deep copy of a table definition
+func copyTable(src *model.Table) *model.Table {
+	t := &model.Table{
+		Schema:  src.Schema,
+		Name:    src.Name,
+		Columns: make(map[string]*model.Column, len(src.Columns)),
+		Indexes: make([]*model.Index, 0, len(src.Indexes)),
+	}
+	for name, col := range src.Columns {
+		c := *col
+		t.Columns[name] = &c
+	}
+	for _, idx := range src.Indexes {
+		i := *idx
+		i.Columns = append([]string(nil), idx.Columns...)
+		i.Descending = append([]bool(nil), idx.Descending...)
+		t.Indexes = append(t.Indexes, &i)
+	}
+	return t
+}
@@ -3,67 +3,181 @@ package parser
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
+	"sql-check/internal/config"
 	"sql-check/internal/model"
 
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
+	"github.com/pingcap/tidb/parser/mysql"
 	_ "github.com/pingcap/tidb/parser/test_driver"
+	"github.com/pingcap/tidb/parser/types"
 )
 
 // SQLParser wraps the TiDB parser
 type SQLParser struct {
 	p *parser.Parser
+
+	// LowerCaseTableNames is the lower_case_table_names setting of loaded
+	// schemas, see model.SchemaCtx
+	LowerCaseTableNames int
 }
 
 func NewSQLParser() *SQLParser {
 	return &SQLParser{
-		p: parser.New(),
+		p:                   parser.New(),
+		LowerCaseTableNames: 1,
 	}
 }
 
-// Parse converts a SQL string into an AST
-func (sp *SQLParser) Parse(sql string) (ast.StmtNode, error) {
-	// TiDB parser requires a semicolon or EOF. 
-	// Often extracted fragments might not have it, or might have multiple.
-	// We wrap it in a simple check.
-	stmtNodes, _, err := sp.p.Parse(sql, "", "")
+// Statement is a single statement of a (possibly multi-statement) SQL string
+type Statement struct {
+	Node   ast.StmtNode
+	Index  int // Position of the statement in the SQL string, starting at 0
+	Offset int // Byte offset of the statement text inside the SQL string
+	End    int // Byte offset of the end of the statement text
+
+	// Placeholders are the bind parameters of the statement in their original
+	// spelling. They appear as '?' markers in Node.
+	Placeholders []model.Placeholder
+}
+
+// ParseAll converts a SQL string into one AST per statement
+func (sp *SQLParser) ParseAll(sql string) ([]Statement, error) {
+	// $1, :name and @p1 parameters are rewritten into '?' first.
+	// Offsets are kept intact, so positions below refer to the original SQL.
+	normalized, placeholders := NormalizePlaceholders(sql)
+
+	// TiDB parser requires a semicolon or EOF between statements.
+	stmtNodes, _, err := sp.p.Parse(normalized, "", "")
 	if err != nil {
-		return nil, err
+		pe := newParseError(normalized, err)
+		if pe.Near != "" && pe.Offset+len(pe.Near) <= len(sql) {
+			pe.Near = sql[pe.Offset : pe.Offset+len(pe.Near)]
+		}
+		return nil, pe
 	}
 	if len(stmtNodes) == 0 {
 		return nil, fmt.Errorf("no valid SQL found")
 	}
-	// For now, we return the first statement found
-	return stmtNodes[0], nil
+
+	stmts := make([]Statement, 0, len(stmtNodes))
+	offset := 0
+	for i, node := range stmtNodes {
+		// Statement texts are slices of the input in order, each one
+		// including the whitespace and comments that precede it.
+		text := node.Text()
+		start := offset
+		if idx := strings.Index(normalized[offset:], text); idx >= 0 {
+			start = offset + idx
+			offset = start + len(text)
+		}
+		stmt := Statement{
+			Node:   node,
+			Index:  i,
+			Offset: start + leadingTrivia(text),
+			End:    start + len(text),
+		}
+		for _, p := range placeholders {
+			if p.Offset >= start && p.Offset < start+len(text) {
+				stmt.Placeholders = append(stmt.Placeholders, p)
+			}
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
 }
 
-// LoadSchema reads a SQL file and populates the SchemaCtx
-func (sp *SQLParser) LoadSchema(path string) (*model.SchemaCtx, error) {
-	content, err := os.ReadFile(path)
+// leadingTrivia returns the length of the whitespace and comments at the start of sql
+func leadingTrivia(sql string) int {
+	i := 0
+	for i < len(sql) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(sql[i])):
+			i++
+		case strings.HasPrefix(sql[i:], "--") || sql[i] == '#':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return len(sql)
+			}
+			i += end + 1
+		case strings.HasPrefix(sql[i:], "/*") && !strings.HasPrefix(sql[i:], "/*!"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return len(sql)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// Parse converts a SQL string into an AST.
+// Only the first statement is returned, use ParseAll for multi-statement SQL.
+func (sp *SQLParser) Parse(sql string) (ast.StmtNode, error) {
+	stmts, err := sp.ParseAll(sql)
 	if err != nil {
 		return nil, err
 	}
+	return stmts[0].Node, nil
+}
+
+// LoadSchema reads a SQL file, or a directory of migrations, and populates
+// the SchemaCtx
+func (sp *SQLParser) LoadSchema(path string) (*model.SchemaCtx, error) {
+	return sp.LoadSchemas([]string{path})
+}
+
+// LoadSchemas reads several SQL files into a single SchemaCtx.
+// Later files override tables defined by earlier ones. A directory is read
+// as migrations, replayed in the order of their versions. A path may be
+// prefixed with "database=" to load its tables into that database.
+func (sp *SQLParser) LoadSchemas(paths []string) (*model.SchemaCtx, error) {
+	schema := model.NewSchemaCtx(sp.LowerCaseTableNames)
 
-	schema := &model.SchemaCtx{
-		Tables: make(map[string]*model.Table),
+	for _, arg := range paths {
+		database, path := config.SplitSchemaPath(arg)
+		files := []string{path}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if files, err = MigrationFiles(path); err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range files {
+			// USE only lasts until the end of the file
+			replay := &ddlReplay{schema: schema, database: database}
+			if err := sp.loadSchemaFile(file, replay); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return schema, nil
+}
+
+func (sp *SQLParser) loadSchemaFile(path string, replay *ddlReplay) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
 	}
 
 	// Parse the whole schema file
 	// Note: Parse returns []ast.StmtNode
 	stmts, _, err := sp.p.Parse(string(content), "", "")
 	if err != nil {
-		return nil, fmt.Errorf("schema parse error: %w", err)
+		return fmt.Errorf("schema parse error in %s: %w", path, err)
 	}
 
 	for _, stmt := range stmts {
-		if createTable, ok := stmt.(*ast.CreateTableStmt); ok {
-			table := parseCreateTable(createTable)
-			schema.Tables[table.Name] = table
-		}
+		replay.apply(stmt)
 	}
 
-	return schema, nil
+	return nil
 }
 
 func parseCreateTable(node *ast.CreateTableStmt) *model.Table {
@@ -75,42 +189,164 @@ func parseCreateTable(node *ast.CreateTableStmt) *model.Table {
 
 	// 1. Columns
 	for _, col := range node.Cols {
-		t.Columns[col.Name.Name.O] = &model.Column{
-			Name: col.Name.Name.O,
-			Type: col.Tp.String(), // Simplified type
+		addColumn(t, col)
+	}
+
+	// 2. Constraints (PK, Unique, etc defined inline or at bottom)
+	for _, cons := range node.Constraints {
+		if idx := indexFromConstraint(cons); idx != nil {
+			addIndex(t, idx)
 		}
+	}
 
-		// Check for inline PRIMARY KEY
-		for _, opt := range col.Options {
-			if opt.Tp == ast.ColumnOptionPrimaryKey {
-				t.Indexes = append(t.Indexes, &model.Index{
-					Name:    "PRIMARY",
-					Unique:  true,
-					Columns: []string{col.Name.Name.O},
-				})
-			}
+	return t
+}
+
+// addColumn adds (or replaces) a column definition, with its inline keys
+func addColumn(t *model.Table, col *ast.ColumnDef) {
+	c := columnDef(col)
+	t.Columns[c.Name] = c
+	for _, idx := range t.Indexes {
+		if idx.Name == "PRIMARY" && slices.ContainsFunc(idx.Columns, func(name string) bool { return strings.EqualFold(name, c.Name) }) {
+			c.Nullable = false // Columns modified in place stay in the primary key
 		}
 	}
 
-	// 2. Constraints (PK, Unique, etc defined inline or at bottom)
-	for _, cons := range node.Constraints {
-		switch cons.Tp {
-		case ast.ConstraintPrimaryKey, ast.ConstraintKey, ast.ConstraintIndex, ast.ConstraintUniq:
-			idx := &model.Index{
-				Name:    cons.Name,
-				Unique:  cons.Tp == ast.ConstraintPrimaryKey || cons.Tp == ast.ConstraintUniq,
-				Columns: make([]string, 0),
-			}
-			if idx.Name == "" && cons.Tp == ast.ConstraintPrimaryKey {
-				idx.Name = "PRIMARY"
-			}
-			// Extract columns
-			for _, keyCol := range cons.Keys {
-				idx.Columns = append(idx.Columns, keyCol.Column.Name.O)
+	// Check for inline PRIMARY KEY / UNIQUE
+	for _, opt := range col.Options {
+		switch opt.Tp {
+		case ast.ColumnOptionPrimaryKey:
+			c.Nullable = false
+			t.Indexes = append(t.Indexes, &model.Index{
+				Name:    "PRIMARY",
+				Unique:  true,
+				Columns: []string{col.Name.Name.O},
+			})
+		case ast.ColumnOptionUniqKey:
+			t.Indexes = append(t.Indexes, &model.Index{
+				Name:    col.Name.Name.O,
+				Unique:  true,
+				Columns: []string{col.Name.Name.O},
+			})
+		}
+	}
+}
+
+// columnDef converts a column definition into the schema model
+func columnDef(col *ast.ColumnDef) *model.Column {
+	ft := col.Tp
+	c := &model.Column{
+		Name:      col.Name.Name.O,
+		Type:      ft.String(),
+		BaseType:  types.TypeToStr(ft.GetType(), ft.GetCharset()),
+		Unsigned:  mysql.HasUnsignedFlag(ft.GetFlag()),
+		Nullable:  true,
+		Charset:   ft.GetCharset(),
+		Collation: ft.GetCollate(),
+	}
+	if flen := ft.GetFlen(); flen != types.UnspecifiedLength {
+		c.Length = flen
+	}
+	if decimal := ft.GetDecimal(); decimal != types.UnspecifiedLength {
+		c.Decimals = decimal
+	}
+
+	for _, opt := range col.Options {
+		switch opt.Tp {
+		case ast.ColumnOptionNotNull:
+			c.Nullable = false
+		case ast.ColumnOptionNull:
+			c.Nullable = true
+		case ast.ColumnOptionDefaultValue:
+			def := restore(opt.Expr)
+			c.Default = &def
+		case ast.ColumnOptionAutoIncrement:
+			c.AutoIncrement = true
+		case ast.ColumnOptionCollate:
+			c.Collation = opt.StrValue
+		case ast.ColumnOptionGenerated:
+			c.Generated = restore(opt.Expr)
+			c.GeneratedStored = opt.Stored
+		}
+	}
+	return c
+}
+
+// restore returns the SQL text of a node, empty if it cannot be restored
+func restore(node ast.Node) string {
+	var sb strings.Builder
+	if err := node.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags|format.RestoreStringWithoutCharset|format.RestoreSpacesAroundBinaryOperation, &sb)); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// addIndex adds an index to the table. Primary key columns are NOT NULL.
+func addIndex(t *model.Table, idx *model.Index) {
+	if idx.Name == "PRIMARY" {
+		for _, name := range idx.Columns {
+			if c, ok := t.Columns[name]; ok {
+				c.Nullable = false
 			}
-			t.Indexes = append(t.Indexes, idx)
 		}
 	}
+	t.Indexes = append(t.Indexes, idx)
+}
 
-	return t
+// indexFromConstraint returns the index defined by a key constraint, nil
+// for other constraints (foreign keys, checks)
+func indexFromConstraint(cons *ast.Constraint) *model.Index {
+	switch cons.Tp {
+	case ast.ConstraintPrimaryKey, ast.ConstraintKey, ast.ConstraintIndex, ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+	default:
+		return nil
+	}
+
+	idx := &model.Index{
+		Name:       cons.Name,
+		Unique:     cons.Tp == ast.ConstraintPrimaryKey || cons.Tp == ast.ConstraintUniq || cons.Tp == ast.ConstraintUniqKey || cons.Tp == ast.ConstraintUniqIndex,
+		Columns:    indexColumns(cons.Keys),
+		Descending: indexDescending(cons.Keys),
+	}
+	if cons.Tp == ast.ConstraintPrimaryKey {
+		idx.Name = "PRIMARY"
+	}
+	if idx.Name == "" && len(idx.Columns) > 0 {
+		// MySQL names unnamed indexes after their first column
+		idx.Name = idx.Columns[0]
+		if model.IsIndexExpression(idx.Name) {
+			idx.Name = "functional_index"
+		}
+	}
+	return idx
+}
+
+// indexColumns returns the column names of index key parts. Expression
+// parts of functional indexes are returned in parentheses, see model.Index.
+func indexColumns(keys []*ast.IndexPartSpecification) []string {
+	cols := make([]string, 0, len(keys))
+	for _, keyCol := range keys {
+		switch {
+		case keyCol.Column != nil:
+			cols = append(cols, keyCol.Column.Name.O)
+		case keyCol.Expr != nil:
+			cols = append(cols, "("+restore(keyCol.Expr)+")")
+		}
+	}
+	return cols
+}
+
+// indexDescending returns the DESC flags of index key parts, nil if there
+// are none
+func indexDescending(keys []*ast.IndexPartSpecification) []bool {
+	var desc []bool
+	for i, keyCol := range keys {
+		if keyCol.Desc {
+			if desc == nil {
+				desc = make([]bool, len(keys))
+			}
+			desc[i] = true
+		}
+	}
+	return desc
 }
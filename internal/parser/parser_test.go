@@ -1,8 +1,15 @@
 package parser
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/test_driver"
 )
 
 func TestSQLParser_Parse(t *testing.T) {
@@ -96,3 +103,534 @@ func TestSQLParser_LoadSchema(t *testing.T) {
 		t.Errorf("Expected 2 indexes, got %d", len(table.Indexes))
 	}
 }
+
+func TestSQLParser_ParseErrorPosition(t *testing.T) {
+	parser := NewSQLParser()
+
+	_, err := parser.Parse("SELECT id\nFROM users WHERE")
+	pe, ok := AsParseError(err)
+	if !ok {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if pe.Line != 2 || pe.Column != 17 || pe.Offset != 26 {
+		t.Errorf("Unexpected position line=%d column=%d offset=%d", pe.Line, pe.Column, pe.Offset)
+	}
+
+	_, err = parser.Parse("SELECT * FORM users")
+	pe, ok = AsParseError(err)
+	if !ok {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if pe.Near != "FORM users" || pe.Offset != 9 {
+		t.Errorf("Unexpected near=%q offset=%d", pe.Near, pe.Offset)
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		sql  string
+		high bool
+	}{
+		{"SELECT * FROM", true},
+		{"DELETE FROM users WHERE id = ?", true},
+		{"UPDATE users SET", true},
+		{"SELECTING items is fun", false},
+		{"Select a file", false},
+		{"Select the file you want to upload from the list.", false},
+		{"INVALID SQL syntax", false},
+	}
+
+	for _, tt := range tests {
+		if got := Confidence(tt.sql); (got >= 0.5) != tt.high {
+			t.Errorf("Confidence(%q) = %.2f, want high=%v", tt.sql, got, tt.high)
+		}
+	}
+}
+
+func TestSQLParser_ParseAll(t *testing.T) {
+	parser := NewSQLParser()
+
+	sql := "DELETE FROM tmp;\n  UPDATE users SET x = 1; -- next\n SELECT 1"
+	stmts, err := parser.ParseAll(sql)
+	if err != nil {
+		t.Fatalf("ParseAll() error = %v", err)
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(stmts))
+	}
+
+	wantTexts := []string{"DELETE FROM tmp", "UPDATE users SET x = 1", "SELECT 1"}
+	for i, stmt := range stmts {
+		if stmt.Index != i {
+			t.Errorf("statement %d: got index %d", i, stmt.Index)
+		}
+		if got := strings.TrimRight(sql[stmt.Offset:stmt.End], ";"); got != wantTexts[i] {
+			t.Errorf("statement %d: range %d-%d holds %q, want %q", i, stmt.Offset, stmt.End, got, wantTexts[i])
+		}
+	}
+}
+
+func TestNormalizePlaceholders(t *testing.T) {
+	tests := []struct {
+		name  string
+		sql   string
+		want  string
+		names []string
+	}{
+		{
+			name:  "Postgres style",
+			sql:   "SELECT id FROM users WHERE id = $1 AND status = $2",
+			want:  "SELECT id FROM users WHERE id = ?  AND status = ? ",
+			names: []string{"$1", "$2"},
+		},
+		{
+			name:  "sqlx named",
+			sql:   "UPDATE users SET name = :name WHERE id = :user_id",
+			want:  "UPDATE users SET name = ?     WHERE id = ?       ",
+			names: []string{":name", ":user_id"},
+		},
+		{
+			name:  "MSSQL style",
+			sql:   "DELETE FROM users WHERE id = @p1",
+			want:  "DELETE FROM users WHERE id = ?  ",
+			names: []string{"@p1"},
+		},
+		{
+			name:  "Dollar signs in identifiers are kept",
+			sql:   "SELECT price$1, a$$2 FROM t WHERE id = $3",
+			want:  "SELECT price$1, a$$2 FROM t WHERE id = ? ",
+			names: []string{"$3"},
+		},
+		{
+			name:  "User variables are kept",
+			sql:   "SELECT @rownum := @rownum + 1 AS n, @p1x, @page FROM users",
+			want:  "SELECT @rownum := @rownum + 1 AS n, @p1x, @page FROM users",
+			names: nil,
+		},
+		{
+			name:  "Question marks and literals are kept",
+			sql:   "SELECT ':no', '$1' FROM t WHERE a = ? AND b = '@x' AND c = @@version",
+			want:  "SELECT ':no', '$1' FROM t WHERE a = ? AND b = '@x' AND c = @@version",
+			names: []string{"?"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, placeholders := NormalizePlaceholders(tt.sql)
+			if got != tt.want {
+				t.Errorf("NormalizePlaceholders() got %q, want %q", got, tt.want)
+			}
+			var names []string
+			for _, p := range placeholders {
+				names = append(names, p.Name)
+				if !strings.HasPrefix(tt.sql[p.Offset:], p.Name) {
+					t.Errorf("placeholder %s has wrong offset %d", p.Name, p.Offset)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.names, ",") {
+				t.Errorf("got placeholders %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestSQLParser_ParsePlaceholders(t *testing.T) {
+	parser := NewSQLParser()
+
+	sql := "SELECT id FROM users WHERE email = :email AND id = $2"
+	stmts, err := parser.ParseAll(sql)
+	if err != nil {
+		t.Fatalf("ParseAll() error = %v", err)
+	}
+
+	var names []string
+	stmts[0].Node.Accept(&paramCollector{fn: func(p *test_driver.ParamMarkerExpr) {
+		for _, ph := range stmts[0].Placeholders {
+			if ph.Offset == p.Offset {
+				names = append(names, ph.Name)
+			}
+		}
+	}})
+
+	if strings.Join(names, ",") != ":email,$2" {
+		t.Errorf("Param markers map to %v", names)
+	}
+}
+
+func TestSQLParser_ParseUserVariables(t *testing.T) {
+	parser := NewSQLParser()
+
+	for _, sql := range []string{
+		"SET @x = 1",
+		"SELECT @rownum := @rownum + 1 AS n FROM users",
+	} {
+		stmts, err := parser.ParseAll(sql)
+		if err != nil {
+			t.Errorf("ParseAll(%q) error = %v", sql, err)
+			continue
+		}
+		if len(stmts[0].Placeholders) != 0 {
+			t.Errorf("ParseAll(%q) found placeholders %v", sql, stmts[0].Placeholders)
+		}
+	}
+}
+
+type paramCollector struct {
+	fn func(p *test_driver.ParamMarkerExpr)
+}
+
+func (v *paramCollector) Enter(in ast.Node) (ast.Node, bool) {
+	if p, ok := in.(*test_driver.ParamMarkerExpr); ok {
+		v.fn(p)
+	}
+	return in, false
+}
+
+func (v *paramCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+func TestMigrationFiles(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"000010_add_orders.up.sql",
+		"000002_add_email.up.sql",
+		"000002_add_email.down.sql",
+		"V1.10__later.sql",
+		"V1.2__earlier.sql",
+		"U1.2__earlier.sql",
+		"R__views.sql",
+		"seed.sql",
+		"README.md",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := MigrationFiles(dir)
+	if err != nil {
+		t.Fatalf("MigrationFiles() error = %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, filepath.Base(f))
+	}
+
+	want := "V1.2__earlier.sql,V1.10__later.sql,000002_add_email.up.sql,000010_add_orders.up.sql,R__views.sql,seed.sql"
+	if strings.Join(got, ",") != want {
+		t.Errorf("MigrationFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestSQLParser_LoadSchemaMigrations(t *testing.T) {
+	dir := t.TempDir()
+	migrations := map[string]string{
+		"000001_init.up.sql": `
+			CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(255), legacy INT, KEY idx_legacy (legacy));
+			CREATE TABLE tmp (id INT);`,
+		"000001_init.down.sql": `DROP TABLE users;`,
+		"000002_users.up.sql": `
+			ALTER TABLE users ADD COLUMN email VARCHAR(255), DROP COLUMN legacy;
+			ALTER TABLE users MODIFY COLUMN name VARCHAR(64);
+			CREATE UNIQUE INDEX idx_email ON users (email);
+			CREATE TABLE IF NOT EXISTS users (id INT);`,
+		"000003_rename.up.sql": `
+			RENAME TABLE users TO accounts;
+			ALTER TABLE accounts ADD INDEX idx_name (name), RENAME INDEX idx_email TO uk_email;
+			DROP TABLE tmp;`,
+	}
+	for name, content := range migrations {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schema, err := NewSQLParser().LoadSchema(dir)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+
+	if len(schema.Tables) != 1 {
+		t.Fatalf("Expected only 'accounts', got %d tables", len(schema.Tables))
+	}
+	table, ok := schema.Tables["accounts"]
+	if !ok {
+		t.Fatalf("Table 'accounts' not found")
+	}
+	if table.Name != "accounts" {
+		t.Errorf("Table name = %q", table.Name)
+	}
+	if _, ok := table.Columns["legacy"]; ok {
+		t.Errorf("Dropped column 'legacy' still present")
+	}
+	if len(table.Columns) != 3 {
+		t.Errorf("Expected 3 columns, got %d", len(table.Columns))
+	}
+
+	var indexes []string
+	for _, idx := range table.Indexes {
+		indexes = append(indexes, fmt.Sprintf("%s(%s)", idx.Name, strings.Join(idx.Columns, ",")))
+	}
+	// idx_legacy lost its only column with it
+	if want := "PRIMARY(id),uk_email(email),idx_name(name)"; strings.Join(indexes, ",") != want {
+		t.Errorf("Indexes = %v, want %v", indexes, want)
+	}
+}
+
+func TestSQLParser_LoadSchemaRedefinedColumns(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+		CREATE TABLE t (id INT PRIMARY KEY, code VARCHAR(10) UNIQUE, name VARCHAR(10));
+		ALTER TABLE t MODIFY id BIGINT PRIMARY KEY;
+		ALTER TABLE t MODIFY code VARCHAR(20) UNIQUE;
+		ALTER TABLE t CHANGE name title VARCHAR(20) UNIQUE;
+		ALTER TABLE t ADD INDEX idx_lower ((LOWER(title)));
+		ALTER TABLE t MODIFY title VARCHAR(30);`
+	if err := os.WriteFile(filepath.Join(dir, "001.up.sql"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := NewSQLParser().LoadSchema(dir)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+
+	var indexes []string
+	for _, idx := range schema.Tables["t"].Indexes {
+		indexes = append(indexes, fmt.Sprintf("%s(%s)", idx.Name, strings.Join(idx.Columns, ",")))
+	}
+	// Inline keys of redefined columns replace the ones they had, and
+	// expression key parts survive changes of their column
+	if want := "PRIMARY(id),code(code),title(title),idx_lower((LOWER(`title`)))"; strings.Join(indexes, ",") != want {
+		t.Errorf("Indexes = %v, want %v", indexes, want)
+	}
+}
+
+func TestSQLParser_LoadSchemaColumnCase(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+		CREATE TABLE t (ID INT PRIMARY KEY, Email VARCHAR(255), Name VARCHAR(10), Code INT);
+		ALTER TABLE t DROP COLUMN email;
+		ALTER TABLE t MODIFY COLUMN name VARCHAR(64);
+		ALTER TABLE t MODIFY id BIGINT;
+		ALTER TABLE t RENAME COLUMN code TO ref;`
+	if err := os.WriteFile(filepath.Join(dir, "001.up.sql"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := NewSQLParser().LoadSchema(dir)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+
+	var names []string
+	for name := range schema.Tables["t"].Columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// Column names are case-insensitive, so the statements change the
+	// declared columns instead of adding new ones
+	if want := "id,name,ref"; strings.Join(names, ",") != want {
+		t.Errorf("Columns = %v, want %v", names, want)
+	}
+	if col := schema.Tables["t"].Columns["name"]; col.Length != 64 {
+		t.Errorf("name = %+v", col)
+	}
+	if col := schema.Tables["t"].Columns["id"]; col.Nullable {
+		t.Errorf("id should stay in the primary key, got %+v", col)
+	}
+}
+
+func TestSQLParser_LoadSchemaColumns(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schema.sql")
+	content := `
+		CREATE TABLE orders (
+			id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+			code VARCHAR(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+			status ENUM('new', 'paid') DEFAULT 'new',
+			amount DECIMAL(10,2),
+			note TEXT NULL,
+			total DECIMAL(12,2) AS (amount * 2) STORED,
+			PRIMARY KEY (id)
+		);`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := NewSQLParser().LoadSchema(path)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	cols := schema.Tables["orders"].Columns
+
+	id := cols["id"]
+	if id.BaseType != "bigint" || !id.Unsigned || id.Nullable || !id.AutoIncrement || !id.IsNumeric() {
+		t.Errorf("id = %+v", id)
+	}
+
+	code := cols["code"]
+	if code.BaseType != "varchar" || code.Length != 32 || code.Nullable || code.Charset != "utf8mb4" || code.Collation != "utf8mb4_bin" || !code.IsString() {
+		t.Errorf("code = %+v", code)
+	}
+
+	status := cols["status"]
+	if status.BaseType != "enum" || !status.Nullable || status.Default == nil || *status.Default != "'new'" {
+		t.Errorf("status = %+v", status)
+	}
+
+	amount := cols["amount"]
+	if amount.BaseType != "decimal" || amount.Length != 10 || amount.Decimals != 2 || amount.Default != nil {
+		t.Errorf("amount = %+v", amount)
+	}
+
+	if note := cols["note"]; !note.IsString() || !note.Nullable {
+		t.Errorf("note = %+v", note)
+	}
+
+	total := cols["total"]
+	if total.Generated != "`amount` * 2" || !total.GeneratedStored {
+		t.Errorf("total = %+v", total)
+	}
+}
+
+func TestSQLParser_LoadSchemaDatabases(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"shop.sql": `
+			CREATE TABLE Users (id INT PRIMARY KEY, name VARCHAR(64));
+			CREATE TABLE accounts (id INT);`,
+		"billing.sql": `
+			CREATE TABLE invoices (id INT);
+			USE billing;
+			CREATE TABLE accounts (id INT, balance INT);
+			CREATE TABLE shop.orders (id INT);
+			ALTER TABLE accounts ADD INDEX idx_balance (balance);`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := NewSQLParser()
+	schema, err := p.LoadSchemas([]string{"shop=" + filepath.Join(dir, "shop.sql"), filepath.Join(dir, "billing.sql")})
+	if err != nil {
+		t.Fatalf("LoadSchemas() error = %v", err)
+	}
+
+	var keys []string
+	for key := range schema.Tables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if want := "billing.accounts,invoices,shop.accounts,shop.orders,shop.users"; strings.Join(keys, ",") != want {
+		t.Errorf("Tables = %v, want %v", keys, want)
+	}
+
+	lookups := []struct {
+		database, name string
+		want           string // "database.name" of the table found, empty if none
+	}{
+		{"", "USERS", "shop.users"},
+		{"Shop", "users", "shop.users"},
+		{"billing", "users", ""},
+		{"", "accounts", ""}, // In two databases
+		{"billing", "accounts", "billing.accounts"},
+		{"", "invoices", ".invoices"},
+		{"shop", "invoices", ".invoices"}, // Loaded without a database
+	}
+	for _, l := range lookups {
+		table, ok := schema.Lookup(l.database, l.name)
+		got := ""
+		if ok {
+			got = table.Schema + "." + table.Name
+		}
+		if got != l.want {
+			t.Errorf("Lookup(%q, %q) = %q, want %q", l.database, l.name, got, l.want)
+		}
+	}
+
+	if accounts, _ := schema.Lookup("billing", "accounts"); len(accounts.Indexes) != 1 {
+		t.Errorf("ALTER TABLE after USE should apply to billing.accounts, got %+v", accounts.Indexes)
+	}
+
+	schema.DefaultDatabase = "billing"
+	if table, ok := schema.Lookup("", "accounts"); !ok || table.Schema != "billing" {
+		t.Errorf("Lookup() should prefer the default database, got %+v", table)
+	}
+
+	// lower_case_table_names=0 is case-sensitive
+	p.LowerCaseTableNames = 0
+	schema, err = p.LoadSchemas([]string{"shop=" + filepath.Join(dir, "shop.sql")})
+	if err != nil {
+		t.Fatalf("LoadSchemas() error = %v", err)
+	}
+	if _, ok := schema.Lookup("shop", "users"); ok {
+		t.Errorf("Lookup() should be case-sensitive")
+	}
+	if _, ok := schema.Lookup("shop", "Users"); !ok {
+		t.Errorf("Lookup() should find Users")
+	}
+}
+
+func TestSQLParser_LoadSchemaFunctionalIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	content := `
+		CREATE TABLE users (id INT, email VARCHAR(255), INDEX ((LOWER(email)), id));
+		CREATE INDEX idx_day ON users ((DATE(created_at)));`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := NewSQLParser().LoadSchema(path)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	indexes := schema.Tables["users"].Indexes
+	if len(indexes) != 2 {
+		t.Fatalf("Indexes = %+v", indexes)
+	}
+	if idx := indexes[0]; idx.Name != "functional_index" || strings.Join(idx.Columns, ",") != "(LOWER(`email`)),id" {
+		t.Errorf("Index = %+v", idx)
+	}
+	if idx := indexes[1]; idx.Name != "idx_day" || strings.Join(idx.Columns, ",") != "(DATE(`created_at`))" {
+		t.Errorf("Index = %+v", idx)
+	}
+}
+
+func TestNormalizeExpr(t *testing.T) {
+	same := []string{"LOWER(`email`)", "lower(u.email)", "(Lower(EMAIL))"}
+	want := "lower(`email`)"
+	for _, s := range same {
+		expr, err := ParseExpr(s)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q) error = %v", s, err)
+		}
+		if got := NormalizeExpr(expr); got != want {
+			t.Errorf("NormalizeExpr(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestSQLParser_LoadSchemaDescendingIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	content := `
+		CREATE TABLE orders (id INT, user_id INT, note TEXT, created_at DATETIME, KEY idx_user_created (user_id, note(10), created_at DESC));
+		ALTER TABLE orders DROP COLUMN note;`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := NewSQLParser().LoadSchema(path)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	idx := schema.Tables["orders"].Indexes[0]
+	if strings.Join(idx.Columns, ",") != "user_id,created_at" || idx.IsDescending(0) || !idx.IsDescending(1) {
+		t.Errorf("Index = %+v", idx)
+	}
+}
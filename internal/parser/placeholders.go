@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"sql-check/internal/model"
+	"strings"
+)
+
+// NormalizePlaceholders rewrites Postgres ($1), named (:user_id) and MSSQL
+// (@p1) bind parameters into the '?' markers the TiDB parser understands.
+// Each marker is padded with spaces to the length of the original parameter,
+// so byte offsets in the returned SQL are the same as in the input.
+// All bind parameters found, including plain '?', are returned in order.
+func NormalizePlaceholders(sql string) (string, []model.Placeholder) {
+	var out []byte // lazily allocated copy, only when something is rewritten
+	var placeholders []model.Placeholder
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = SkipQuoted(sql, i)
+			continue
+		case strings.HasPrefix(sql[i:], "--") || c == '#':
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(sql)
+			}
+			continue
+		case strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(sql)
+			}
+			continue
+		case c == '?':
+			placeholders = append(placeholders, model.Placeholder{Name: "?", Offset: i})
+			continue
+		}
+
+		end := i
+		switch {
+		case c == '$' && (i == 0 || (!isIdentChar(sql[i-1]) && sql[i-1] != '$')):
+			// Not inside an identifier such as price$1
+			end = scanWhile(sql, i+1, isDigit)
+		case c == ':' && (i == 0 || sql[i-1] != ':') && i+1 < len(sql) && isIdentStart(sql[i+1]):
+			end = scanWhile(sql, i+1, isIdentChar)
+		case c == '@' && (i == 0 || sql[i-1] != '@') && i+2 < len(sql) && (sql[i+1] == 'p' || sql[i+1] == 'P') && isDigit(sql[i+2]):
+			// Only @p1 style, other names are MySQL user variables
+			end = scanWhile(sql, i+2, isDigit)
+			if end < len(sql) && isIdentChar(sql[end]) {
+				end = i
+			}
+		}
+		if end <= i+1 {
+			continue
+		}
+
+		if out == nil {
+			out = []byte(sql)
+		}
+		out[i] = '?'
+		for j := i + 1; j < end; j++ {
+			out[j] = ' '
+		}
+		placeholders = append(placeholders, model.Placeholder{Name: sql[i:end], Offset: i})
+		i = end - 1
+	}
+
+	if out == nil {
+		return sql, placeholders
+	}
+	return string(out), placeholders
+}
+
+// SkipQuoted returns the index of the quote closing the SQL literal or
+// quoted identifier starting at i, len(sql) if it is not closed. Backslash
+// escapes and doubled quotes are skipped.
+func SkipQuoted(sql string, i int) int {
+	quote := sql[i]
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			// A doubled quote is an escaped quote
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(sql)
+}
+
+func scanWhile(s string, i int, pred func(byte) bool) int {
+	for i < len(s) && pred(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
@@ -1,13 +1,28 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
 )
 
 // ExtractTableNames extracts all table names mentioned in a SQL statement.
 // Currently supports Select, Update, and Delete statements.
 func ExtractTableNames(node ast.StmtNode) []string {
-	var tables []string
+	var names []string
+	for _, tn := range ExtractTables(node) {
+		names = append(names, tn.Name.O)
+	}
+	return names
+}
+
+// ExtractTables is ExtractTableNames returning the table name nodes, which
+// also carry the database of qualified names
+func ExtractTables(node ast.StmtNode) []*ast.TableName {
+	var tables []*ast.TableName
 	
 	switch stmt := node.(type) {
 	case *ast.SelectStmt:
@@ -31,7 +46,7 @@ func ExtractTableNames(node ast.StmtNode) []string {
 	return tables
 }
 
-func extractTableRefs(join *ast.Join, tables *[]string) {
+func extractTableRefs(join *ast.Join, tables *[]*ast.TableName) {
 	if join == nil {
 		return
 	}
@@ -44,12 +59,47 @@ func extractTableRefs(join *ast.Join, tables *[]string) {
 	}
 }
 
-func extractTableSource(r ast.ResultSetNode, tables *[]string) {
+func extractTableSource(r ast.ResultSetNode, tables *[]*ast.TableName) {
 	if ts, ok := r.(*ast.TableSource); ok {
 		if tn, ok := ts.Source.(*ast.TableName); ok {
-			*tables = append(*tables, tn.Name.O)
+			*tables = append(*tables, tn)
 		}
 	} else if join, ok := r.(*ast.Join); ok {
 		extractTableRefs(join, tables)
 	}
 }
+
+// ParseExpr parses a standalone expression, such as the key part of a
+// functional index or the expression of a generated column
+func ParseExpr(expr string) (ast.ExprNode, error) {
+	stmt, err := parser.New().ParseOneStmt("SELECT "+expr, "", "")
+	if err != nil {
+		return nil, err
+	}
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok || sel.Fields == nil || len(sel.Fields.Fields) != 1 || sel.Fields.Fields[0].Expr == nil {
+		return nil, fmt.Errorf("not a single expression: %s", expr)
+	}
+	return sel.Fields.Fields[0].Expr, nil
+}
+
+// NormalizeExpr returns the text of an expression in a form that compares
+// equal for the same expression however it is written: lower case, without
+// table and database qualifiers and with redundant parentheses removed
+func NormalizeExpr(expr ast.ExprNode) string {
+	for {
+		paren, ok := expr.(*ast.ParenthesesExpr)
+		if !ok {
+			break
+		}
+		expr = paren.Expr
+	}
+	var sb strings.Builder
+	flags := format.RestoreStringSingleQuotes | format.RestoreKeyWordLowercase | format.RestoreNameLowercase |
+		format.RestoreNameBackQuotes | format.RestoreStringWithoutCharset | format.RestoreSpacesAroundBinaryOperation |
+		format.RestoreWithoutSchemaName | format.RestoreWithoutTableName
+	if err := expr.Restore(format.NewRestoreCtx(flags, &sb)); err != nil {
+		return ""
+	}
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sql-check/internal/model"
+	"strings"
+)
+
+// GitHubActionsReporter prints workflow commands, which GitHub Actions turns
+// into annotations on the pull request diff
+type GitHubActionsReporter struct {
+	OutputFile string // Empty writes to stdout
+}
+
+func NewGitHubActionsReporter(filename string) *GitHubActionsReporter {
+	return &GitHubActionsReporter{OutputFile: filename}
+}
+
+func (r *GitHubActionsReporter) Report(issues []model.Issue) error {
+	out, err := openOutput(r.OutputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	active, _ := model.SplitSuppressed(issues)
+	for _, issue := range active {
+		// Paths are relative to the working directory, which is the
+		// repository root in a workflow
+		loc := issue.Location()
+		path, _ := model.RelPath(".", loc.FilePath)
+		position := fmt.Sprintf("line=%d", loc.Line)
+		if loc.Column > 0 {
+			position += fmt.Sprintf(",col=%d", loc.Column)
+		}
+		if loc.EndLine > loc.Line {
+			position += fmt.Sprintf(",endLine=%d", loc.EndLine)
+		}
+		_, err := fmt.Fprintf(out, "::%s file=%s,%s,title=%s::%s\n",
+			githubCommand(issue.Level),
+			escapeProperty(path),
+			position,
+			escapeProperty(issue.Type),
+			escapeData(issue.Message+"\n"+issue.Suggestion))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// githubCommand maps a risk level to a workflow command
+func githubCommand(level model.RiskLevel) string {
+	switch level {
+	case model.RiskLevelFatal:
+		return "error"
+	case model.RiskLevelWarning:
+		return "warning"
+	}
+	return "notice"
+}
+
+// escapeData escapes the message of a workflow command
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// GitLabReporter writes a GitLab Code Quality report
+type GitLabReporter struct {
+	OutputFile string // Empty writes to stdout
+	Root       string // Scan root, fingerprints use paths relative to it
+}
+
+func NewGitLabReporter(filename, root string) *GitLabReporter {
+	return &GitLabReporter{OutputFile: filename, Root: root}
+}
+
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
+
+func (r *GitLabReporter) Report(issues []model.Issue) error {
+	active, _ := model.SplitSuppressed(issues)
+
+	report := make([]gitlabIssue, 0, len(active))
+	seen := make(map[string]int)
+	for _, issue := range active {
+		// GitLab requires unique fingerprints, identical SQL of the same
+		// type in one file is told apart by its occurrence
+		fingerprint := issue.RelativeTo(r.Root).Fingerprint()
+		seen[fingerprint]++
+		if n := seen[fingerprint]; n > 1 {
+			fingerprint = fmt.Sprintf("%s-%d", fingerprint, n)
+		}
+
+		loc := issue.Location()
+		path, _ := model.RelPath(".", loc.FilePath)
+		report = append(report, gitlabIssue{
+			Description: issue.Message,
+			CheckName:   issue.Type,
+			Fingerprint: fingerprint,
+			Severity:    gitlabSeverity(issue.Level),
+			Location: gitlabLocation{
+				Path:  path,
+				Lines: gitlabLines{Begin: loc.Line, End: loc.EndLine},
+			},
+		})
+	}
+
+	out, err := openOutput(r.OutputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// gitlabSeverity maps a risk level to a Code Quality severity
+func gitlabSeverity(level model.RiskLevel) string {
+	switch level {
+	case model.RiskLevelFatal:
+		return "critical"
+	case model.RiskLevelWarning:
+		return "major"
+	}
+	return "minor"
+}
@@ -0,0 +1,101 @@
+package reporter
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sql-check/internal/model"
+	"strings"
+	"testing"
+)
+
+func TestEscapeGitHubCommands(t *testing.T) {
+	if got, want := escapeData("100% sure\r\nnext: a, b"), "100%25 sure%0D%0Anext: a, b"; got != want {
+		t.Errorf("escapeData() = %q, want %q", got, want)
+	}
+	if got, want := escapeProperty("C:\\src\\a,b 50%\n"), "C%3A\\src\\a%2Cb 50%25%0A"; got != want {
+		t.Errorf("escapeProperty() = %q, want %q", got, want)
+	}
+}
+
+func TestGitHubActionsReporter_Report(t *testing.T) {
+	issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelWarning, "dao/a,b:c.go", 3, "SELECT * FROM users")
+	issue.Message = "Avoid 100% of columns"
+	issue.Suggestion = "List columns:\nid, name"
+	suppressed := testIssue("no_where_clause", "UNSAFE_DELETE", model.RiskLevelFatal, "dao/a.go", 5, "DELETE FROM tmp")
+	suppressed.Suppressed = true
+
+	content := string(readReport(t, func(path string) error {
+		return NewGitHubActionsReporter(path).Report([]model.Issue{issue, suppressed})
+	}))
+
+	want := "::warning file=dao/a%2Cb%3Ac.go,line=3,col=1,title=SELECT_STAR::Avoid 100%25 of columns%0AList columns:%0Aid, name\n"
+	if content != want {
+		t.Errorf("Got\n%q\nwant\n%q", content, want)
+	}
+}
+
+func TestGitLabReporter_Fingerprints(t *testing.T) {
+	sql := "SELECT * FROM users"
+	issues := []model.Issue{
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "dao/a.go", 3, sql),
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "dao/a.go", 9, sql),
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "dao/a.go", 12, sql),
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "dao/b.go", 3, sql),
+	}
+	suppressed := testIssue("no_where_clause", "UNSAFE_DELETE", model.RiskLevelFatal, "dao/a.go", 5, "DELETE FROM tmp")
+	suppressed.Suppressed = true
+
+	content := readReport(t, func(path string) error {
+		return NewGitLabReporter(path, "").Report(append(issues, suppressed))
+	})
+
+	var report []gitlabIssue
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(report) != len(issues) {
+		t.Fatalf("Expected %d issues without the suppressed one, got %d", len(issues), len(report))
+	}
+
+	seen := make(map[string]bool)
+	for i, issue := range report {
+		if seen[issue.Fingerprint] {
+			t.Errorf("Duplicate fingerprint %s", issue.Fingerprint)
+		}
+		seen[issue.Fingerprint] = true
+		if issue.Location.Lines.Begin != issues[i].Segment.Location.Line || issue.Severity != "minor" {
+			t.Errorf("Unexpected issue %+v", issue)
+		}
+	}
+	if !strings.HasPrefix(report[1].Fingerprint, report[0].Fingerprint) {
+		t.Errorf("Repeated issues should extend the fingerprint of the first: %s, %s", report[0].Fingerprint, report[1].Fingerprint)
+	}
+}
+
+func TestGitLabReporter_RelativeFingerprints(t *testing.T) {
+	fingerprint := func(root string) string {
+		issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, filepath.Join(root, "dao", "a.go"), 3, "SELECT * FROM users")
+		content := readReport(t, func(path string) error { return NewGitLabReporter(path, root).Report([]model.Issue{issue}) })
+		var report []gitlabIssue
+		if err := json.Unmarshal(content, &report); err != nil || len(report) != 1 {
+			t.Fatalf("Unexpected report %s: %v", content, err)
+		}
+		return report[0].Fingerprint
+	}
+
+	first := fingerprint("/src/a")
+	if other := fingerprint("/src/b"); other != first {
+		t.Errorf("Fingerprint depends on the checkout directory: %s != %s", other, first)
+	}
+	issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "/src/a/dao/a.go", 3, "SELECT * FROM users")
+	if sarif := sarifReport(t, "/src/a", nil, []model.Issue{issue}).Runs[0].Results[0].PartialFingerprints[fingerprintKey]; sarif != first {
+		t.Errorf("GitLab fingerprint %s differs from the SARIF one %s", first, sarif)
+	}
+}
+
+func TestGitLabReporter_Empty(t *testing.T) {
+	content := readReport(t, func(path string) error { return NewGitLabReporter(path, "").Report(nil) })
+	if strings.TrimSpace(string(content)) != "[]" {
+		t.Errorf("An empty report should be an empty array, got %q", content)
+	}
+}
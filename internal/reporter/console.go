@@ -18,14 +18,18 @@ func NewConsoleReporter() *ConsoleReporter {
 }
 
 func (r *ConsoleReporter) Report(issues []model.Issue) error {
+	issues, suppressed := model.SplitSuppressed(issues)
 	if len(issues) == 0 {
 		fmt.Fprintln(r.out, color.GreenString("✔ No SQL issues found! Great job."))
+		if len(suppressed) > 0 {
+			fmt.Fprintf(r.out, "  (%d issues suppressed by inline comments)\n", len(suppressed))
+		}
 		return nil
 	}
 
 	for _, issue := range issues {
-		// Format: file:line: [LEVEL] Message
-		loc := fmt.Sprintf("%s:%d", issue.Segment.Location.FilePath, issue.Segment.Location.Line)
+		// Format: file:line:column: [LEVEL] Message
+		loc := issue.Location().String()
 		
 		var levelColor *color.Color
 		switch issue.Level {
@@ -42,13 +46,24 @@ func (r *ConsoleReporter) Report(issues []model.Issue) error {
 		fmt.Fprintf(r.out, "%s: [%s] %s\n", loc, levelColor.Sprint(issue.Level), issue.Message)
 		
 		// Print code snippet context if possible (simplified here)
-		fmt.Fprintf(r.out, "\tCode: %s\n", color.CyanString(truncate(issue.Segment.SQL, 80)))
+		// For multi-statement segments, start at the offending statement
+		code := issue.Segment.SQL
+		if issue.StatementOffset > 0 && issue.StatementOffset < len(code) {
+			code = code[issue.StatementOffset:]
+		}
+		fmt.Fprintf(r.out, "\tCode: %s\n", color.CyanString(truncate(code, 80)))
+		if issue.StatementIndex > 0 {
+			fmt.Fprintf(r.out, "\tStatement: #%d in segment\n", issue.StatementIndex+1)
+		}
 		fmt.Fprintf(r.out, "\tSuggestion: %s\n", issue.Suggestion)
 		fmt.Fprintln(r.out)
 	}
 	
 	// Summary
 	fmt.Fprintf(r.out, "\n%s found %d issues.\n", color.RedString("✘"), len(issues))
+	if len(suppressed) > 0 {
+		fmt.Fprintf(r.out, "  (%d more suppressed by inline comments)\n", len(suppressed))
+	}
 	return nil
 }
 
@@ -35,6 +35,9 @@ const htmlTemplate = `
 		.code-block { background: #282c34; color: #abb2bf; padding: 10px; border-radius: 4px; font-family: monospace; overflow-x: auto; }
 		.location { font-size: 0.9em; color: #666; margin-bottom: 5px; }
 		.suggestion { font-weight: bold; color: #2e7d32; margin-top: 10px; }
+		.suppressed { opacity: 0.7; }
+		.suppressed .issue-header { background-color: #f5f5f5; border-left: 5px solid #9e9e9e; }
+		.reason { font-style: italic; color: #555; margin-top: 5px; }
 		.meta { font-size: 0.85rem; color: #777; margin-top: 20px; text-align: center; }
 	</style>
 </head>
@@ -43,14 +46,15 @@ const htmlTemplate = `
 		<h1>SQL Scan Report</h1>
 		<div class="summary">
 			<strong>Scan Date:</strong> {{ .Date }}<br>
-			<strong>Total Issues:</strong> {{ .TotalCount }}
+			<strong>Total Issues:</strong> {{ .TotalCount }}<br>
+			<strong>Suppressed:</strong> {{ len .Suppressed }}
 		</div>
 
 		{{ range .Issues }}
 		<div class="issue">
 			<div class="issue-header {{ .Level }}">
 				<span><strong>[{{ .Level }}]</strong> {{ .Type }}</span>
-				<span class="location">{{ .Segment.Location }}</span>
+				<span class="location">{{ .Location }}</span>
 			</div>
 			<div class="issue-body">
 				<div class="message">{{ .Message }}</div>
@@ -64,6 +68,22 @@ const htmlTemplate = `
 			<p>No SQL issues found.</p>
 		</div>
 		{{ end }}
+
+		{{ if .Suppressed }}
+		<h2>Suppressed Issues</h2>
+		{{ range .Suppressed }}
+		<div class="issue suppressed">
+			<div class="issue-header">
+				<span><strong>[{{ .Level }}]</strong> {{ .Type }}</span>
+				<span class="location">{{ .Location }}</span>
+			</div>
+			<div class="issue-body">
+				<div class="message">{{ .Message }}</div>
+				<div class="reason">Reason: {{ if .SuppressReason }}{{ .SuppressReason }}{{ else }}<em>none given</em>{{ end }}</div>
+			</div>
+		</div>
+		{{ end }}
+		{{ end }}
 		
 		<div class="meta">Generated by SQL-Check Tool</div>
 	</div>
@@ -75,6 +95,7 @@ type reportData struct {
 	Date       string
 	TotalCount int
 	Issues     []model.Issue
+	Suppressed []model.Issue
 }
 
 func (r *HTMLReporter) Report(issues []model.Issue) error {
@@ -89,10 +110,12 @@ func (r *HTMLReporter) Report(issues []model.Issue) error {
 		return err
 	}
 
+	active, suppressed := model.SplitSuppressed(issues)
 	data := reportData{
 		Date:       time.Now().Format(time.RFC1123),
-		TotalCount: len(issues),
-		Issues:     issues,
+		TotalCount: len(active),
+		Issues:     active,
+		Suppressed: suppressed,
 	}
 
 	if err := t.Execute(f, data); err != nil {
@@ -0,0 +1,153 @@
+package reporter
+
+import (
+	"encoding/json"
+	"sql-check/internal/model"
+	"time"
+)
+
+// JSONSchemaVersion is the version of the json and ndjson report formats.
+// It is bumped whenever a field is removed or changes meaning; new fields
+// may be added without a bump.
+const JSONSchemaVersion = 1
+
+// JSONReporter writes all issues as one JSON document with a summary
+type JSONReporter struct {
+	OutputFile string // Empty writes to stdout
+	Root       string // Scan root, file paths are made relative to it
+}
+
+func NewJSONReporter(filename, root string) *JSONReporter {
+	return &JSONReporter{OutputFile: filename, Root: root}
+}
+
+// NDJSONReporter writes one JSON object per issue and line, so results can
+// be streamed and processed line by line
+type NDJSONReporter struct {
+	OutputFile string // Empty writes to stdout
+	Root       string // Scan root, file paths are made relative to it
+}
+
+func NewNDJSONReporter(filename, root string) *NDJSONReporter {
+	return &NDJSONReporter{OutputFile: filename, Root: root}
+}
+
+type jsonReport struct {
+	SchemaVersion int         `json:"schema_version"`
+	Tool          string      `json:"tool"`
+	GeneratedAt   string      `json:"generated_at"` // RFC 3339
+	Summary       jsonSummary `json:"summary"`
+	Issues        []jsonIssue `json:"issues"`
+}
+
+// jsonSummary counts the issues that are not suppressed
+type jsonSummary struct {
+	Total      int            `json:"total"`
+	Suppressed int            `json:"suppressed"`
+	ByLevel    map[string]int `json:"by_level"`
+	ByType     map[string]int `json:"by_type"`
+}
+
+type jsonIssue struct {
+	Type           string `json:"type"`
+	Rule           string `json:"rule"`
+	Level          string `json:"level"`
+	Message        string `json:"message"`
+	Suggestion     string `json:"suggestion"`
+	File           string `json:"file"`
+	Line           int    `json:"line"`
+	Column         int    `json:"column,omitempty"`
+	EndLine        int    `json:"end_line,omitempty"`
+	EndColumn      int    `json:"end_column,omitempty"`
+	Language       string `json:"language"`
+	SQL            string `json:"sql"`
+	StatementIndex int    `json:"statement_index"`
+	Fingerprint    string `json:"fingerprint"`
+	Suppressed     bool   `json:"suppressed"`
+	SuppressReason string `json:"suppress_reason,omitempty"`
+}
+
+// ndjsonRecord is a line of the ndjson report
+type ndjsonRecord struct {
+	SchemaVersion int `json:"schema_version"`
+	jsonIssue
+}
+
+func (r *JSONReporter) Report(issues []model.Issue) error {
+	report := jsonReport{
+		SchemaVersion: JSONSchemaVersion,
+		Tool:          "sql-check",
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		Summary: jsonSummary{
+			ByLevel: map[string]int{
+				string(model.RiskLevelFatal):      0,
+				string(model.RiskLevelWarning):    0,
+				string(model.RiskLevelSuggestion): 0,
+			},
+			ByType: map[string]int{},
+		},
+		Issues: make([]jsonIssue, 0, len(issues)),
+	}
+
+	for _, issue := range issues {
+		report.Issues = append(report.Issues, toJSONIssue(issue, r.Root))
+		if issue.Suppressed {
+			report.Summary.Suppressed++
+			continue
+		}
+		report.Summary.Total++
+		report.Summary.ByLevel[string(issue.Level)]++
+		report.Summary.ByType[issue.Type]++
+	}
+
+	out, err := openOutput(r.OutputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func (r *NDJSONReporter) Report(issues []model.Issue) error {
+	out, err := openOutput(r.OutputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// Encode writes a newline after every value
+	enc := json.NewEncoder(out)
+	for _, issue := range issues {
+		if err := enc.Encode(ndjsonRecord{SchemaVersion: JSONSchemaVersion, jsonIssue: toJSONIssue(issue, r.Root)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toJSONIssue converts an issue, with its file path relative to root
+func toJSONIssue(issue model.Issue, root string) jsonIssue {
+	issue = issue.RelativeTo(root)
+	loc := issue.Location()
+	return jsonIssue{
+		Type:           issue.Type,
+		Rule:           issue.Rule,
+		Level:          string(issue.Level),
+		Message:        issue.Message,
+		Suggestion:     issue.Suggestion,
+		File:           loc.FilePath,
+		Line:           loc.Line,
+		Column:         loc.Column,
+		EndLine:        loc.EndLine,
+		EndColumn:      loc.EndColumn,
+		Language:       issue.Segment.Language,
+		SQL:            issue.Segment.SQL,
+		StatementIndex: issue.StatementIndex,
+		Fingerprint:    issue.Fingerprint(),
+		Suppressed:     issue.Suppressed,
+		SuppressReason: issue.SuppressReason,
+	}
+}
@@ -0,0 +1,141 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sql-check/internal/model"
+	"testing"
+)
+
+func TestJSONReporter_Fields(t *testing.T) {
+	issues := []model.Issue{
+		testIssue("no_where_clause", "UNSAFE_DELETE", model.RiskLevelFatal, "repo.go", 7, "DELETE FROM users"),
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "repo.go", 9, "SELECT * FROM users"),
+	}
+	issues[1].Suppressed, issues[1].SuppressReason = true, "legacy"
+
+	content := readReport(t, func(path string) error { return NewJSONReporter(path, "").Report(issues) })
+
+	var report map[string]interface{}
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	for _, key := range []string{"schema_version", "tool", "generated_at", "summary", "issues"} {
+		if _, ok := report[key]; !ok {
+			t.Errorf("Missing top-level field %q", key)
+		}
+	}
+	if report["schema_version"] != float64(JSONSchemaVersion) || report["tool"] != "sql-check" {
+		t.Errorf("Unexpected header %v / %v", report["schema_version"], report["tool"])
+	}
+
+	summary := report["summary"].(map[string]interface{})
+	if summary["total"] != float64(1) || summary["suppressed"] != float64(1) {
+		t.Errorf("Unexpected summary %v", summary)
+	}
+	if byLevel := summary["by_level"].(map[string]interface{}); byLevel["FATAL"] != float64(1) || byLevel["SUGGESTION"] != float64(0) {
+		t.Errorf("Suppressed issues must not be counted by level: %v", byLevel)
+	}
+
+	list := report["issues"].([]interface{})
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 issues, got %d", len(list))
+	}
+	first := list[0].(map[string]interface{})
+	want := map[string]interface{}{
+		"type": "UNSAFE_DELETE", "rule": "no_where_clause", "level": "FATAL",
+		"file": "repo.go", "line": float64(7), "column": float64(1),
+		"language": "go", "sql": "DELETE FROM users", "statement_index": float64(0),
+		"suppressed": false,
+	}
+	for key, value := range want {
+		if first[key] != value {
+			t.Errorf("Field %q = %v, want %v", key, first[key], value)
+		}
+	}
+	for _, key := range []string{"message", "suggestion", "fingerprint"} {
+		if s, ok := first[key].(string); !ok || s == "" {
+			t.Errorf("Field %q missing or empty", key)
+		}
+	}
+	if _, ok := first["suppress_reason"]; ok {
+		t.Error("suppress_reason should be omitted when empty")
+	}
+	if second := list[1].(map[string]interface{}); second["suppressed"] != true || second["suppress_reason"] != "legacy" {
+		t.Errorf("Unexpected suppression fields %v / %v", second["suppressed"], second["suppress_reason"])
+	}
+}
+
+func TestJSONReporter_RelativePaths(t *testing.T) {
+	record := func(root string) map[string]interface{} {
+		issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, filepath.Join(root, "store", "user.go"), 3, "SELECT * FROM users")
+		content := readReport(t, func(path string) error { return NewNDJSONReporter(path, root).Report([]model.Issue{issue}) })
+		var record map[string]interface{}
+		if err := json.Unmarshal(content, &record); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		return record
+	}
+
+	first := record("/src/a")
+	if first["file"] != "store/user.go" {
+		t.Errorf("file = %v, want the path relative to the root", first["file"])
+	}
+	if other := record("/src/b"); other["fingerprint"] != first["fingerprint"] {
+		t.Errorf("Fingerprint depends on the checkout directory: %v != %v", other["fingerprint"], first["fingerprint"])
+	}
+
+	issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "/src/a/store/user.go", 3, "SELECT * FROM users")
+	if sarif := sarifReport(t, "/src/a", nil, []model.Issue{issue}).Runs[0].Results[0].PartialFingerprints[fingerprintKey]; sarif != first["fingerprint"] {
+		t.Errorf("JSON fingerprint %v differs from the SARIF one %s", first["fingerprint"], sarif)
+	}
+}
+
+func TestJSONReporter_Empty(t *testing.T) {
+	content := readReport(t, func(path string) error { return NewJSONReporter(path, "").Report(nil) })
+
+	var report jsonReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if !bytes.Contains(content, []byte(`"issues": []`)) {
+		t.Errorf("An empty report should have an empty issues array, got:\n%s", content)
+	}
+	if report.Summary.Total != 0 || len(report.Summary.ByLevel) != 3 {
+		t.Errorf("Unexpected summary %+v", report.Summary)
+	}
+}
+
+func TestNDJSONReporter_Lines(t *testing.T) {
+	issues := []model.Issue{
+		testIssue("no_where_clause", "UNSAFE_DELETE", model.RiskLevelFatal, "repo.go", 7, "DELETE\nFROM users"),
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "repo.go", 9, "SELECT * FROM users"),
+	}
+
+	content := readReport(t, func(path string) error { return NewNDJSONReporter(path, "").Report(issues) })
+
+	lines := bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+	if len(lines) != len(issues) {
+		t.Fatalf("Expected one line per issue, got %d lines:\n%s", len(lines), content)
+	}
+	for i, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("Line %d is not a JSON object: %v", i+1, err)
+		}
+		if record["schema_version"] != float64(JSONSchemaVersion) {
+			t.Errorf("Line %d: schema_version = %v", i+1, record["schema_version"])
+		}
+		if record["type"] != issues[i].Type || record["line"] != float64(issues[i].Segment.Location.Line) {
+			t.Errorf("Line %d: got %v at line %v", i+1, record["type"], record["line"])
+		}
+	}
+}
+
+func TestNDJSONReporter_Empty(t *testing.T) {
+	content := readReport(t, func(path string) error { return NewNDJSONReporter(path, "").Report(nil) })
+	if len(content) != 0 {
+		t.Errorf("An empty ndjson report should be empty, got %q", content)
+	}
+}
@@ -0,0 +1,248 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"sql-check/internal/model"
+	"strings"
+)
+
+// JUnit suites can be grouped by file or by rule
+const (
+	JUnitGroupByFile = "file"
+	JUnitGroupByRule = "rule"
+)
+
+// JUnitReporter writes a JUnit XML report for CI systems that render test
+// results. Every FATAL or WARNING issue is a failing test case, every
+// audited segment without such issues a passing one.
+type JUnitReporter struct {
+	OutputFile string // Empty writes to stdout
+	GroupBy    string // JUnitGroupByFile (default) or JUnitGroupByRule
+
+	segments []model.SQLSegment // All audited segments, clean ones included
+	rules    []string           // Rule names, for passing cases per rule
+}
+
+func NewJUnitReporter(filename, groupBy string, segments []model.SQLSegment, rules []model.Rule) *JUnitReporter {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name())
+	}
+	return &JUnitReporter{OutputFile: filename, GroupBy: groupBy, segments: segments, rules: names}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func (r *JUnitReporter) Report(issues []model.Issue) error {
+	// Issues by the segment they were found in
+	bySegment := make(map[string][]model.Issue)
+	for _, issue := range issues {
+		key := segmentKey(issue.Segment)
+		bySegment[key] = append(bySegment[key], issue)
+	}
+
+	// Issues whose segment was not handed in still get reported
+	segments := append([]model.SQLSegment(nil), r.segments...)
+	known := make(map[string]bool, len(segments))
+	for _, seg := range segments {
+		known[segmentKey(seg)] = true
+	}
+	for _, issue := range issues {
+		if key := segmentKey(issue.Segment); !known[key] {
+			known[key] = true
+			segments = append(segments, issue.Segment)
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		a, b := segments[i].Location, segments[j].Location
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.Line < b.Line
+	})
+
+	suites := make(map[string]*junitTestSuite)
+	suite := func(name string) *junitTestSuite {
+		if s, ok := suites[name]; ok {
+			return s
+		}
+		suites[name] = &junitTestSuite{Name: name}
+		return suites[name]
+	}
+
+	for _, seg := range segments {
+		found := bySegment[segmentKey(seg)]
+		if r.GroupBy == JUnitGroupByRule {
+			r.addRuleCases(suite, seg, found)
+		} else {
+			r.addFileCases(suite(seg.Location.FilePath), seg, found)
+		}
+	}
+
+	names := make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := junitTestSuites{Name: "sql-check"}
+	for _, name := range names {
+		s := suites[name]
+		for _, c := range s.Cases {
+			s.Tests++
+			if c.Failure != nil {
+				s.Failures++
+			}
+			if c.Skipped != nil {
+				s.Skipped++
+			}
+		}
+		report.Tests += s.Tests
+		report.Failures += s.Failures
+		report.Skipped += s.Skipped
+		report.Suites = append(report.Suites, *s)
+	}
+
+	out, err := openOutput(r.OutputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := fmt.Fprint(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out)
+	return err
+}
+
+// addFileCases adds the cases of a segment to the suite of its file: one per
+// failing or suppressed issue, or a single passing case.
+func (r *JUnitReporter) addFileCases(s *junitTestSuite, seg model.SQLSegment, found []model.Issue) {
+	passed := true
+	var notes []string
+	for _, issue := range found {
+		if c, ok := issueCase(issue, seg.Location.FilePath, fmt.Sprintf("%s line %d", issue.Type, seg.Location.Line)); ok {
+			s.Cases = append(s.Cases, c)
+			passed = passed && c.Failure == nil
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("[%s] %s", issue.Level, issue.Message))
+	}
+	if passed {
+		c := junitTestCase{
+			Name:      fmt.Sprintf("line %d: %s", seg.Location.Line, truncate(oneLine(seg.SQL), 60)),
+			ClassName: seg.Location.FilePath,
+		}
+		// Suggestions do not fail the case but are kept as output
+		if len(notes) > 0 {
+			c.SystemOut = &junitOutput{Text: strings.Join(notes, "\n")}
+		}
+		s.Cases = append(s.Cases, c)
+	}
+}
+
+// addRuleCases adds the cases of a segment to the suite of every rule: the
+// rule's failing or suppressed issues, or a passing case if it found none.
+func (r *JUnitReporter) addRuleCases(suite func(string) *junitTestSuite, seg model.SQLSegment, found []model.Issue) {
+	name := fmt.Sprintf("%s:%d", seg.Location.FilePath, seg.Location.Line)
+
+	failed := make(map[string]bool)
+	for _, issue := range found {
+		rule := issueRule(issue)
+		if c, ok := issueCase(issue, rule, fmt.Sprintf("%s %s", issue.Type, name)); ok {
+			suite(rule).Cases = append(suite(rule).Cases, c)
+			failed[rule] = true
+		}
+	}
+	for _, rule := range r.rules {
+		if !failed[rule] {
+			suite(rule).Cases = append(suite(rule).Cases, junitTestCase{Name: name, ClassName: rule})
+		}
+	}
+}
+
+// issueCase returns the test case of a FATAL or WARNING issue. Suppressed
+// issues are skipped cases. ok is false for issues that do not fail.
+func issueCase(issue model.Issue, className, name string) (junitTestCase, bool) {
+	c := junitTestCase{Name: name, ClassName: className}
+	switch {
+	case issue.Suppressed:
+		msg := "suppressed by inline comment"
+		if issue.SuppressReason != "" {
+			msg += ": " + issue.SuppressReason
+		}
+		c.Skipped = &junitSkipped{Message: msg}
+	case issue.Level == model.RiskLevelFatal || issue.Level == model.RiskLevelWarning:
+		c.Failure = &junitFailure{
+			Message: issue.Message,
+			Type:    issue.Type,
+			Body: fmt.Sprintf("[%s] %s\nLocation: %s\nSQL: %s\nSuggestion: %s\n",
+				issue.Level, issue.Message, issue.Location(), issue.Segment.SQL, issue.Suggestion),
+		}
+	default:
+		return c, false
+	}
+	return c, true
+}
+
+func issueRule(issue model.Issue) string {
+	if issue.Rule != "" {
+		return issue.Rule
+	}
+	return issue.Type
+}
+
+// segmentKey identifies a segment by its location and text
+func segmentKey(seg model.SQLSegment) string {
+	return fmt.Sprintf("%s:%d:%s", seg.Location.FilePath, seg.Location.Line, seg.SQL)
+}
+
+// oneLine collapses whitespace runs, including newlines, into single spaces
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,128 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"sql-check/internal/model"
+	"strings"
+	"testing"
+)
+
+func junitReport(t *testing.T, groupBy string, segments []model.SQLSegment, rules []model.Rule, issues []model.Issue) junitTestSuites {
+	t.Helper()
+	content := readReport(t, func(path string) error {
+		return NewJUnitReporter(path, groupBy, segments, rules).Report(issues)
+	})
+	var report junitTestSuites
+	if err := xml.Unmarshal(content, &report); err != nil {
+		t.Fatalf("Invalid JUnit XML: %v\n%s", err, content)
+	}
+	return report
+}
+
+// junitFixture returns segments of two files and their issues: a FATAL and a
+// SUGGESTION in a.go, a WARNING and a suppressed FATAL in b.go, and a clean
+// segment in each file
+func junitFixture() ([]model.SQLSegment, []model.Rule, []model.Issue) {
+	deleteAll := testIssue("no_where_clause", "UNSAFE_DELETE", model.RiskLevelFatal, "a.go", 3, "DELETE FROM users")
+	star := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "a.go", 5, "SELECT * FROM users")
+	miss := testIssue("index_miss", "INDEX_MISS", model.RiskLevelWarning, "b.go", 2, "SELECT id FROM orders WHERE amount = ?")
+	suppressed := testIssue("no_where_clause", "UNSAFE_UPDATE", model.RiskLevelFatal, "b.go", 4, "UPDATE tmp SET a = 1")
+	suppressed.Suppressed, suppressed.SuppressReason = true, "staging"
+
+	clean := func(file string, line int) model.SQLSegment {
+		return model.SQLSegment{SQL: "SELECT id FROM users WHERE id = ?", Location: model.Location{FilePath: file, Line: line}}
+	}
+	segments := []model.SQLSegment{
+		deleteAll.Segment, star.Segment, clean("a.go", 7),
+		miss.Segment, suppressed.Segment, clean("b.go", 6),
+	}
+	rules := []model.Rule{
+		testRule{"no_where_clause", model.RiskLevelFatal},
+		testRule{"select_star", model.RiskLevelSuggestion},
+		testRule{"index_miss", model.RiskLevelWarning},
+	}
+	return segments, rules, []model.Issue{deleteAll, star, miss, suppressed}
+}
+
+func TestJUnitReporter_GroupByFile(t *testing.T) {
+	segments, rules, issues := junitFixture()
+	report := junitReport(t, JUnitGroupByFile, segments, rules, issues)
+
+	if len(report.Suites) != 2 || report.Suites[0].Name != "a.go" || report.Suites[1].Name != "b.go" {
+		t.Fatalf("Expected one suite per file, got %+v", report.Suites)
+	}
+	// a.go: the failing DELETE, the SELECT * passing with a note, the clean
+	// SELECT. b.go: the failing SELECT, the skipped UPDATE, which otherwise
+	// passes, and the clean SELECT.
+	want := []struct{ tests, failures, skipped int }{{3, 1, 0}, {4, 1, 1}}
+	for i, w := range want {
+		s := report.Suites[i]
+		if s.Tests != w.tests || s.Failures != w.failures || s.Skipped != w.skipped || len(s.Cases) != w.tests {
+			t.Errorf("Suite %s: got %d tests, %d failures, %d skipped, want %+v", s.Name, s.Tests, s.Failures, s.Skipped, w)
+		}
+	}
+	if report.Tests != 7 || report.Failures != 2 || report.Skipped != 1 {
+		t.Errorf("Totals: got %d tests, %d failures, %d skipped", report.Tests, report.Failures, report.Skipped)
+	}
+
+	notes := 0
+	for _, c := range report.Suites[0].Cases {
+		if c.SystemOut != nil && strings.Contains(c.SystemOut.Text, "[SUGGESTION]") {
+			notes++
+		}
+		if c.Failure != nil && c.Failure.Type != "UNSAFE_DELETE" {
+			t.Errorf("Only the FATAL issue of a.go should fail, got %s", c.Failure.Type)
+		}
+		if c.ClassName != "a.go" {
+			t.Errorf("Unexpected class name %q", c.ClassName)
+		}
+	}
+	if notes != 1 {
+		t.Errorf("The suggestion should be kept as output of a passing case, found %d", notes)
+	}
+}
+
+func TestJUnitReporter_GroupByRule(t *testing.T) {
+	segments, rules, issues := junitFixture()
+	report := junitReport(t, JUnitGroupByRule, segments, rules, issues)
+
+	suites := make(map[string]junitTestSuite)
+	for _, s := range report.Suites {
+		suites[s.Name] = s
+	}
+	if len(suites) != 3 {
+		t.Fatalf("Expected one suite per rule, got %+v", report.Suites)
+	}
+
+	// Every rule has a case per segment. Suggestions pass, suppressed
+	// issues are skipped.
+	want := map[string]struct{ tests, failures, skipped int }{
+		"no_where_clause": {6, 1, 1},
+		"select_star":     {6, 0, 0},
+		"index_miss":      {6, 1, 0},
+	}
+	for name, w := range want {
+		s := suites[name]
+		if s.Tests != w.tests || s.Failures != w.failures || s.Skipped != w.skipped {
+			t.Errorf("Suite %s: got %d tests, %d failures, %d skipped, want %+v", name, s.Tests, s.Failures, s.Skipped, w)
+		}
+	}
+	if report.Tests != 18 || report.Failures != 2 || report.Skipped != 1 {
+		t.Errorf("Totals: got %d tests, %d failures, %d skipped", report.Tests, report.Failures, report.Skipped)
+	}
+}
+
+func TestJUnitReporter_OnlyFatalAndWarningFail(t *testing.T) {
+	for _, level := range []model.RiskLevel{model.RiskLevelFatal, model.RiskLevelWarning, model.RiskLevelSuggestion} {
+		issue := testIssue("mock", "MOCK", level, "a.go", 1, "SELECT 1")
+		report := junitReport(t, JUnitGroupByFile, nil, nil, []model.Issue{issue})
+
+		wantFailures := 1
+		if level == model.RiskLevelSuggestion {
+			wantFailures = 0
+		}
+		if report.Tests != 1 || report.Failures != wantFailures {
+			t.Errorf("%s issue: got %d tests, %d failures, want 1 and %d", level, report.Tests, report.Failures, wantFailures)
+		}
+	}
+}
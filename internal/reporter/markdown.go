@@ -0,0 +1,192 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"sql-check/internal/model"
+	"strconv"
+	"strings"
+)
+
+// reportLevels are the risk levels in report order
+var reportLevels = []model.RiskLevel{model.RiskLevelFatal, model.RiskLevelWarning, model.RiskLevelSuggestion}
+
+var levelIcons = map[model.RiskLevel]string{
+	model.RiskLevelFatal:      "🔴",
+	model.RiskLevelWarning:    "🟠",
+	model.RiskLevelSuggestion: "🔵",
+}
+
+// MarkdownReporter writes a Markdown report for pull request comments
+type MarkdownReporter struct {
+	OutputFile string // Empty writes to stdout
+	Root       string // Scan root, links use paths relative to it
+	// LinkTemplate turns locations into links, e.g.
+	// "https://github.com/org/repo/blob/main/{path}#L{line}".
+	// Locations are not linked if it is empty.
+	LinkTemplate string
+}
+
+func NewMarkdownReporter(filename, root, linkTemplate string) *MarkdownReporter {
+	return &MarkdownReporter{OutputFile: filename, Root: root, LinkTemplate: linkTemplate}
+}
+
+func (r *MarkdownReporter) Report(issues []model.Issue) error {
+	active, suppressed := model.SplitSuppressed(issues)
+
+	var b strings.Builder
+	b.WriteString("## SQL Check Report\n\n")
+	if len(active) == 0 {
+		b.WriteString("✅ No SQL issues found.\n")
+	} else {
+		fmt.Fprintf(&b, "Found **%d** issues", len(active))
+		if len(suppressed) > 0 {
+			fmt.Fprintf(&b, " (%d suppressed)", len(suppressed))
+		}
+		b.WriteString(".\n\n")
+		r.writeSummary(&b, active)
+		r.writeFiles(&b, active)
+	}
+	if len(suppressed) > 0 {
+		r.writeSuppressed(&b, suppressed)
+	}
+
+	out, err := openOutput(r.OutputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = out.Write([]byte(b.String()))
+	return err
+}
+
+// writeSummary writes the issue counts per rule and level
+func (r *MarkdownReporter) writeSummary(b *strings.Builder, issues []model.Issue) {
+	counts := make(map[string]map[model.RiskLevel]int)
+	totals := make(map[model.RiskLevel]int)
+	for _, issue := range issues {
+		rule := issueRule(issue)
+		if counts[rule] == nil {
+			counts[rule] = make(map[model.RiskLevel]int)
+		}
+		counts[rule][issue.Level]++
+		totals[issue.Level]++
+	}
+
+	rules := make([]string, 0, len(counts))
+	for rule := range counts {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+
+	b.WriteString("| Rule |")
+	for _, level := range reportLevels {
+		fmt.Fprintf(b, " %s %s |", levelIcons[level], level)
+	}
+	b.WriteString(" Total |\n| :--- |")
+	for range reportLevels {
+		b.WriteString(" ---: |")
+	}
+	b.WriteString(" ---: |\n")
+
+	row := func(name string, byLevel map[model.RiskLevel]int) {
+		total := 0
+		fmt.Fprintf(b, "| %s |", name)
+		for _, level := range reportLevels {
+			fmt.Fprintf(b, " %d |", byLevel[level])
+			total += byLevel[level]
+		}
+		fmt.Fprintf(b, " %d |\n", total)
+	}
+	for _, rule := range rules {
+		row("`"+rule+"`", counts[rule])
+	}
+	row("**Total**", totals)
+	b.WriteString("\n")
+}
+
+// writeFiles writes a collapsible section per file
+func (r *MarkdownReporter) writeFiles(b *strings.Builder, issues []model.Issue) {
+	byFile := make(map[string][]model.Issue)
+	for _, issue := range issues {
+		path := issue.Segment.Location.FilePath
+		byFile[path] = append(byFile[path], issue)
+	}
+	files := make([]string, 0, len(byFile))
+	for path := range byFile {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	for _, path := range files {
+		found := byFile[path]
+		sort.SliceStable(found, func(i, j int) bool {
+			return found[i].Location().Line < found[j].Location().Line
+		})
+
+		fmt.Fprintf(b, "<details>\n<summary><code>%s</code> (%d issues)</summary>\n\n", escapeHTML(path), len(found))
+		for _, issue := range found {
+			fmt.Fprintf(b, "#### %s `%s` %s at %s\n\n", levelIcons[issue.Level], issue.Level, issue.Type, r.location(issue.Location()))
+			fmt.Fprintf(b, "%s\n\n", issue.Message)
+			fence := codeFence(issue.Segment.SQL)
+			fmt.Fprintf(b, "%ssql\n%s\n%s\n\n", fence, strings.TrimSpace(issue.Segment.SQL), fence)
+			if issue.Suggestion != "" {
+				fmt.Fprintf(b, "> 💡 %s\n\n", issue.Suggestion)
+			}
+		}
+		b.WriteString("</details>\n\n")
+	}
+}
+
+func (r *MarkdownReporter) writeSuppressed(b *strings.Builder, issues []model.Issue) {
+	fmt.Fprintf(b, "<details>\n<summary>%d suppressed issues</summary>\n\n", len(issues))
+	b.WriteString("| Location | Type | Reason |\n| :--- | :--- | :--- |\n")
+	for _, issue := range issues {
+		reason := issue.SuppressReason
+		if reason == "" {
+			reason = "_none given_"
+		}
+		fmt.Fprintf(b, "| %s | %s | %s |\n", r.location(issue.Location()), issue.Type, escapeCell(reason))
+	}
+	b.WriteString("\n</details>\n")
+}
+
+// location formats a location, as a link if a template is set
+func (r *MarkdownReporter) location(loc model.Location) string {
+	path, _ := model.RelPath(r.Root, loc.FilePath)
+	text := fmt.Sprintf("`%s:%d`", path, loc.Line)
+	if r.LinkTemplate == "" {
+		return text
+	}
+	url := strings.NewReplacer("{path}", path, "{line}", strconv.Itoa(loc.Line)).Replace(r.LinkTemplate)
+	return fmt.Sprintf("[%s](%s)", text, url)
+}
+
+// codeFence returns a backtick fence longer than any backtick run in code
+func codeFence(code string) string {
+	longest, run := 0, 0
+	for _, c := range code {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// escapeCell escapes text for a table cell
+func escapeCell(s string) string {
+	return strings.ReplaceAll(oneLine(s), "|", "\\|")
+}
+
+func escapeHTML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,55 @@
+package reporter
+
+import (
+	"path/filepath"
+	"sql-check/internal/model"
+	"strings"
+	"testing"
+)
+
+func markdownReport(t *testing.T, root, linkTemplate string, issues []model.Issue) string {
+	t.Helper()
+	return string(readReport(t, func(path string) error {
+		return NewMarkdownReporter(path, root, linkTemplate).Report(issues)
+	}))
+}
+
+func TestMarkdownReporter_LinkTemplate(t *testing.T) {
+	root := t.TempDir()
+	issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, filepath.Join(root, "dao", "users.go"), 42, "SELECT * FROM users")
+
+	linked := markdownReport(t, root, "https://example.com/org/repo/blob/main/{path}#L{line}", []model.Issue{issue})
+	if want := "[`dao/users.go:42`](https://example.com/org/repo/blob/main/dao/users.go#L42)"; !strings.Contains(linked, want) {
+		t.Errorf("Expected link %s in:\n%s", want, linked)
+	}
+
+	plain := markdownReport(t, root, "", []model.Issue{issue})
+	if !strings.Contains(plain, "`dao/users.go:42`") || strings.Contains(plain, "](") {
+		t.Errorf("Expected an unlinked location in:\n%s", plain)
+	}
+}
+
+func TestMarkdownReporter_Escaping(t *testing.T) {
+	sql := "SELECT a || b, ```x``` FROM `users` WHERE note = '|'"
+	issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "repo.go", 3, sql)
+	suppressed := testIssue("no_where_clause", "UNSAFE_DELETE", model.RiskLevelFatal, "repo.go", 5, "DELETE FROM tmp")
+	suppressed.Suppressed, suppressed.SuppressReason = true, "a | b\nc"
+
+	report := markdownReport(t, "", "", []model.Issue{issue, suppressed})
+
+	// The SQL is fenced with more backticks than it contains, and kept verbatim
+	if want := "````sql\n" + sql + "\n````"; !strings.Contains(report, want) {
+		t.Errorf("Expected SQL fenced as\n%s\nin:\n%s", want, report)
+	}
+	// Table cells escape pipes and stay on one line
+	if want := "| `repo.go:5` | UNSAFE_DELETE | a \\| b c |"; !strings.Contains(report, want) {
+		t.Errorf("Expected row %s in:\n%s", want, report)
+	}
+}
+
+func TestMarkdownReporter_Empty(t *testing.T) {
+	report := markdownReport(t, "", "", nil)
+	if !strings.Contains(report, "No SQL issues found") || strings.Contains(report, "| Rule |") {
+		t.Errorf("Unexpected empty report:\n%s", report)
+	}
+}
@@ -0,0 +1,21 @@
+package reporter
+
+import (
+	"io"
+	"os"
+)
+
+// openOutput returns the writer a report is written to: the file at path,
+// or stdout if path is empty.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,56 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"sql-check/internal/model"
+	"testing"
+
+	"github.com/pingcap/tidb/parser/ast"
+)
+
+// testRule is a rule that only documents itself
+type testRule struct {
+	name  string
+	level model.RiskLevel
+}
+
+func (r testRule) Name() string { return r.name }
+
+func (r testRule) Description() string { return "Description of " + r.name }
+
+func (r testRule) DefaultLevel() model.RiskLevel { return r.level }
+
+func (r testRule) Check(seg *model.SQLSegment, node ast.StmtNode, schema *model.SchemaCtx) ([]model.Issue, error) {
+	return nil, nil
+}
+
+// testIssue returns an issue of rule in file at line
+func testIssue(rule, typ string, level model.RiskLevel, file string, line int, sql string) model.Issue {
+	return model.Issue{
+		Type:       typ,
+		Level:      level,
+		Message:    typ + " found",
+		Suggestion: "Fix it.",
+		Rule:       rule,
+		Segment: model.SQLSegment{
+			SQL:      sql,
+			Location: model.Location{FilePath: file, Line: line, Column: 1},
+			Language: "go",
+		},
+	}
+}
+
+// readReport runs report with a fresh output file and returns its content
+func readReport(t *testing.T, report func(path string) error) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "report.out")
+	if err := report(path); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
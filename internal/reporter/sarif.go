@@ -0,0 +1,196 @@
+package reporter
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sql-check/internal/model"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// srcRoot is the base id SARIF consumers resolve relative paths against
+	srcRoot = "%SRCROOT%"
+	// fingerprintKey names the fingerprint in partialFingerprints
+	fingerprintKey = "sqlCheckFingerprint/v1"
+)
+
+// SARIFReporter writes issues as a SARIF 2.1.0 log for code scanning tools
+type SARIFReporter struct {
+	OutputFile string       // Empty writes to stdout
+	Root       string       // Scan root, file locations are made relative to it
+	rules      []model.Rule // Catalogue of the rules that ran
+}
+
+func NewSARIFReporter(filename, root string, rules []model.Rule) *SARIFReporter {
+	return &SARIFReporter{OutputFile: filename, Root: root, rules: rules}
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string             `json:"ruleId"`
+	RuleIndex           int                `json:"ruleIndex"`
+	Level               string             `json:"level"`
+	Message             sarifMessage       `json:"message"`
+	Locations           []sarifLocation    `json:"locations"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints"`
+	Suppressions        []sarifSuppression `json:"suppressions,omitempty"`
+	Properties          map[string]string  `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+func (r *SARIFReporter) Report(issues []model.Issue) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "sql-check", Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+
+	ruleIndex := make(map[string]int)
+	addRule := func(rule sarifRule) int {
+		if idx, ok := ruleIndex[rule.ID]; ok {
+			return idx
+		}
+		ruleIndex[rule.ID] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		return ruleIndex[rule.ID]
+	}
+
+	for _, rule := range r.rules {
+		entry := sarifRule{
+			ID:                   rule.Name(),
+			ShortDescription:     sarifMessage{Text: rule.Name()},
+			DefaultConfiguration: sarifConfiguration{Level: "warning"},
+		}
+		if d, ok := rule.(model.RuleDescriber); ok {
+			entry.ShortDescription.Text = d.Description()
+			entry.DefaultConfiguration.Level = sarifLevel(d.DefaultLevel())
+		}
+		addRule(entry)
+	}
+
+	for _, issue := range issues {
+		// Issues of rules outside the catalogue (e.g. parse errors) get an
+		// entry of their own
+		id := issue.Rule
+		if id == "" {
+			id = issue.Type
+		}
+		idx := addRule(sarifRule{
+			ID:                   id,
+			ShortDescription:     sarifMessage{Text: issue.Type},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(issue.Level)},
+		})
+
+		result := sarifResult{
+			RuleID:              id,
+			RuleIndex:           idx,
+			Level:               sarifLevel(issue.Level),
+			Message:             sarifMessage{Text: issue.Message + "\n" + issue.Suggestion},
+			Locations:           []sarifLocation{r.location(issue.Location())},
+			PartialFingerprints: map[string]string{fingerprintKey: issue.RelativeTo(r.Root).Fingerprint()},
+			Properties:          map[string]string{"type": issue.Type},
+		}
+		if issue.Suppressed {
+			result.Suppressions = []sarifSuppression{{Kind: "inSource", Justification: issue.SuppressReason}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	out, err := openOutput(r.OutputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+func (r *SARIFReporter) location(loc model.Location) sarifLocation {
+	artifact := sarifArtifactLocation{URI: filepath.ToSlash(loc.FilePath)}
+	if filepath.IsAbs(loc.FilePath) {
+		artifact.URI = "file://" + artifact.URI
+	}
+	if rel, ok := model.RelPath(r.Root, loc.FilePath); ok {
+		artifact = sarifArtifactLocation{URI: rel, URIBaseID: srcRoot}
+	}
+
+	physical := sarifPhysicalLocation{ArtifactLocation: artifact}
+	if loc.Line > 0 {
+		physical.Region = &sarifRegion{StartLine: loc.Line, StartColumn: loc.Column}
+		if loc.EndLine >= loc.Line && loc.EndColumn > 0 {
+			physical.Region.EndLine, physical.Region.EndColumn = loc.EndLine, loc.EndColumn
+		}
+	}
+	return sarifLocation{PhysicalLocation: physical}
+}
+
+// sarifLevel maps a risk level to a SARIF result level
+func sarifLevel(level model.RiskLevel) string {
+	switch level {
+	case model.RiskLevelFatal:
+		return "error"
+	case model.RiskLevelWarning:
+		return "warning"
+	}
+	return "note"
+}
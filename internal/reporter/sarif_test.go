@@ -0,0 +1,107 @@
+package reporter
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sql-check/internal/model"
+	"testing"
+)
+
+func sarifReport(t *testing.T, root string, rules []model.Rule, issues []model.Issue) sarifLog {
+	t.Helper()
+	content := readReport(t, func(path string) error {
+		return NewSARIFReporter(path, root, rules).Report(issues)
+	})
+	var log sarifLog
+	if err := json.Unmarshal(content, &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v", err)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("Expected 1 run, got %d", len(log.Runs))
+	}
+	return log
+}
+
+func TestSARIFReporter_RuleIndex(t *testing.T) {
+	rules := []model.Rule{
+		testRule{"no_where_clause", model.RiskLevelFatal},
+		testRule{"select_star", model.RiskLevelSuggestion},
+	}
+	issues := []model.Issue{
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "repo.go", 3, "SELECT * FROM users"),
+		testIssue("", "PARSE_ERROR", model.RiskLevelWarning, "repo.go", 5, "SELECT FROM"),
+		testIssue("no_where_clause", "UNSAFE_DELETE", model.RiskLevelFatal, "repo.go", 7, "DELETE FROM users"),
+	}
+
+	run := sarifReport(t, "", rules, issues).Runs[0]
+
+	var ids []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ids = append(ids, rule.ID)
+	}
+	want := []string{"no_where_clause", "select_star", "PARSE_ERROR"}
+	if len(ids) != len(want) {
+		t.Fatalf("Got rules %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Got rules %v, want %v", ids, want)
+		}
+	}
+	if got := run.Tool.Driver.Rules[0].DefaultConfiguration.Level; got != "error" {
+		t.Errorf("Fatal rule has default level %q, want error", got)
+	}
+
+	for _, result := range run.Results {
+		if result.RuleIndex < 0 || result.RuleIndex >= len(ids) || ids[result.RuleIndex] != result.RuleID {
+			t.Errorf("Result %s has ruleIndex %d, which is not its rule in %v", result.RuleID, result.RuleIndex, ids)
+		}
+	}
+}
+
+func TestSARIFReporter_Fingerprints(t *testing.T) {
+	fingerprint := func(root string, line int) string {
+		issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, filepath.Join(root, "repo.go"), line, "SELECT * FROM users")
+		result := sarifReport(t, root, nil, []model.Issue{issue}).Runs[0].Results[0]
+		return result.PartialFingerprints[fingerprintKey]
+	}
+
+	first := fingerprint("/src/a", 3)
+	if first == "" {
+		t.Fatal("Missing partial fingerprint")
+	}
+	if moved := fingerprint("/src/a", 42); moved != first {
+		t.Errorf("Fingerprint changed when the code moved: %s != %s", moved, first)
+	}
+	if other := fingerprint("/src/b", 3); other != first {
+		t.Errorf("Fingerprint depends on the checkout directory: %s != %s", other, first)
+	}
+
+	issue := testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "/src/a/repo.go", 3, "SELECT * FROM orders")
+	if different := sarifReport(t, "/src/a", nil, []model.Issue{issue}).Runs[0].Results[0].PartialFingerprints[fingerprintKey]; different == first {
+		t.Error("Different statements share a fingerprint")
+	}
+}
+
+func TestSARIFReporter_Locations(t *testing.T) {
+	root := t.TempDir()
+	issues := []model.Issue{
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, filepath.Join(root, "dao", "users.go"), 3, "SELECT * FROM users"),
+		testIssue("select_star", "SELECT_STAR", model.RiskLevelSuggestion, "/elsewhere/users.go", 4, "SELECT * FROM users"),
+	}
+
+	results := sarifReport(t, root, nil, issues).Runs[0].Results
+
+	inside := results[0].Locations[0].PhysicalLocation
+	if inside.ArtifactLocation.URI != "dao/users.go" || inside.ArtifactLocation.URIBaseID != srcRoot {
+		t.Errorf("Got location %+v, want dao/users.go relative to %s", inside.ArtifactLocation, srcRoot)
+	}
+	if inside.Region == nil || inside.Region.StartLine != 3 || inside.Region.StartColumn != 1 {
+		t.Errorf("Unexpected region %+v", inside.Region)
+	}
+
+	outside := results[1].Locations[0].PhysicalLocation.ArtifactLocation
+	if outside.URI != "file:///elsewhere/users.go" || outside.URIBaseID != "" {
+		t.Errorf("File outside the root should keep its absolute URI, got %+v", outside)
+	}
+}
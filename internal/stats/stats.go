@@ -0,0 +1,224 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sql-check/internal/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Stats holds estimated table sizes, e.g. taken from a production replica
+type Stats struct {
+	// Tables are keyed by table name, or "database.table"
+	Tables map[string]*TableStats `yaml:"tables"`
+}
+
+// TableStats are the estimates of a single table
+type TableStats struct {
+	Rows int64 `yaml:"rows"`
+	// Indexes maps index names to their cardinality (distinct values)
+	Indexes map[string]int64 `yaml:"indexes"`
+}
+
+// Load reads a statistics file. .yaml, .yml and .json files hold Stats
+// directly; anything else is read as the output of SHOW TABLE STATUS,
+// SHOW INDEX or a query on information_schema.TABLES/STATISTICS, in the
+// mysql client's table, vertical (\G) or tab-separated (-B) format.
+func Load(path string) (*Stats, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s *Stats
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		s, err = parseStructured(content)
+	default:
+		s = ParseDump(content)
+		if len(s.Tables) == 0 {
+			s, err = parseStructured(content)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid statistics %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func parseStructured(content []byte) (*Stats, error) {
+	s := &Stats{}
+	if err := yaml.Unmarshal(content, s); err != nil {
+		return nil, err
+	}
+	if s.Tables == nil {
+		s.Tables = make(map[string]*TableStats)
+	}
+	return s, nil
+}
+
+// Apply attaches the estimates to the tables of the schema. It returns the
+// names of tables that are not in the schema, sorted.
+func (s *Stats) Apply(schema *model.SchemaCtx) []string {
+	var missing []string
+	for name, ts := range s.Tables {
+		database, tableName := "", name
+		if db, t, ok := strings.Cut(name, "."); ok {
+			database, tableName = db, t
+		}
+		table, ok := schema.Lookup(database, tableName)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		table.Rows = ts.Rows
+		for _, idx := range table.Indexes {
+			if card, ok := ts.Indexes[idx.Name]; ok {
+				idx.Cardinality = card
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+var (
+	// +--------+--------+ borders of the mysql table format
+	borderLine = regexp.MustCompile(`^\+[-+]+\+$`)
+	// *************************** 1. row *************************** of \G output
+	verticalRow = regexp.MustCompile(`^\*+ \d+\. row \*+$`)
+)
+
+// ParseDump reads mysql client output of SHOW TABLE STATUS, SHOW INDEX,
+// information_schema.TABLES or information_schema.STATISTICS. Several
+// result sets may follow each other; unrelated ones are ignored.
+func ParseDump(content []byte) *Stats {
+	s := &Stats{Tables: make(map[string]*TableStats)}
+
+	var header []string
+	var vertical map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case verticalRow.MatchString(trimmed):
+			s.addRecord(vertical)
+			vertical = make(map[string]string)
+			continue
+		case vertical != nil:
+			if key, value, ok := strings.Cut(trimmed, ":"); ok {
+				vertical[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+				continue
+			}
+			s.addRecord(vertical)
+			vertical = nil
+		}
+
+		if trimmed == "" || borderLine.MatchString(trimmed) {
+			continue
+		}
+
+		var fields []string
+		if strings.HasPrefix(trimmed, "|") {
+			fields = strings.Split(strings.Trim(trimmed, "|"), "|")
+		} else {
+			fields = strings.Split(line, "\t")
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		if isHeader(fields) {
+			header = make([]string, len(fields))
+			for i, f := range fields {
+				header[i] = strings.ToLower(f)
+			}
+			continue
+		}
+		if header == nil || len(fields) != len(header) {
+			continue
+		}
+		record := make(map[string]string, len(header))
+		for i, key := range header {
+			record[key] = fields[i]
+		}
+		s.addRecord(record)
+	}
+	s.addRecord(vertical)
+
+	return s
+}
+
+// isHeader reports whether fields are the column names of a result set
+// holding table or index statistics
+func isHeader(fields []string) bool {
+	has := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		has[strings.ToLower(f)] = true
+	}
+	return (has["name"] && has["rows"]) || (has["table_name"] && has["table_rows"]) ||
+		(has["key_name"] && has["cardinality"]) || (has["index_name"] && has["cardinality"])
+}
+
+// addRecord takes the estimates out of a result row. Keys are lower case
+// column names.
+func (s *Stats) addRecord(record map[string]string) {
+	if len(record) == 0 {
+		return
+	}
+
+	tableName := firstOf(record, "table_name", "table", "name")
+	if tableName == "" {
+		return
+	}
+	if database := firstOf(record, "table_schema"); database != "" {
+		tableName = database + "." + tableName
+	}
+	ts := s.Tables[tableName]
+	if ts == nil {
+		ts = &TableStats{}
+	}
+
+	if index := firstOf(record, "index_name", "key_name"); index != "" {
+		// SHOW INDEX has a row per key part; the last one counts the whole index
+		card, err := strconv.ParseInt(firstOf(record, "cardinality"), 10, 64)
+		if err != nil {
+			return
+		}
+		if ts.Indexes == nil {
+			ts.Indexes = make(map[string]int64)
+		}
+		if card > ts.Indexes[index] {
+			ts.Indexes[index] = card
+		}
+	} else {
+		// Views have NULL rows
+		rows, err := strconv.ParseInt(firstOf(record, "table_rows", "rows"), 10, 64)
+		if err != nil {
+			return
+		}
+		ts.Rows = rows
+	}
+	s.Tables[tableName] = ts
+}
+
+func firstOf(record map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := record[key]; ok && v != "" && v != "NULL" {
+			return v
+		}
+	}
+	return ""
+}
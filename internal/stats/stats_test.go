@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sql-check/internal/model"
+)
+
+func TestLoad_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.yaml")
+	content := `
+tables:
+  orders:
+    rows: 2000000000
+    indexes:
+      idx_user_id: 150000000
+  settings:
+    rows: 200
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Tables["orders"].Rows != 2000000000 || s.Tables["orders"].Indexes["idx_user_id"] != 150000000 {
+		t.Errorf("orders = %+v", s.Tables["orders"])
+	}
+	if s.Tables["settings"].Rows != 200 {
+		t.Errorf("settings = %+v", s.Tables["settings"])
+	}
+}
+
+func TestLoad_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := os.WriteFile(path, []byte(`{"tables": {"orders": {"rows": 42}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Tables["orders"].Rows != 42 {
+		t.Errorf("orders = %+v", s.Tables["orders"])
+	}
+}
+
+func TestParseDump(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "SHOW TABLE STATUS and SHOW INDEX",
+			content: `
+mysql> SHOW TABLE STATUS;
++--------+--------+---------+------------+------------+
+| Name   | Engine | Version | Row_format | Rows       |
++--------+--------+---------+------------+------------+
+| orders | InnoDB |      10 | Dynamic    | 2000000000 |
+| v_all  | NULL   |    NULL | NULL       |       NULL |
++--------+--------+---------+------------+------------+
+2 rows in set (0.01 sec)
+
+mysql> SHOW INDEX FROM orders;
++--------+------------+-------------+--------------+-------------+-------------+
+| Table  | Non_unique | Key_name    | Seq_in_index | Column_name | Cardinality |
++--------+------------+-------------+--------------+-------------+-------------+
+| orders |          1 | idx_user_at |            1 | user_id     |   150000000 |
+| orders |          1 | idx_user_at |            2 | created_at  |  1900000000 |
++--------+------------+-------------+--------------+-------------+-------------+
+`,
+		},
+		{
+			name: "vertical",
+			content: `*************************** 1. row ***************************
+           Name: orders
+         Engine: InnoDB
+           Rows: 2000000000
+    Create_time: 2024-01-01 10:00:00
+*************************** 1. row ***************************
+        Table: orders
+     Key_name: idx_user_at
+  Cardinality: 1900000000
+`,
+		},
+		{
+			name: "information_schema, tab-separated",
+			content: "TABLE_NAME\tTABLE_ROWS\n" +
+				"orders\t2000000000\n" +
+				"TABLE_NAME\tINDEX_NAME\tSEQ_IN_INDEX\tCARDINALITY\n" +
+				"orders\tidx_user_at\t1\t150000000\n" +
+				"orders\tidx_user_at\t2\t1900000000\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ParseDump([]byte(tt.content))
+			orders := s.Tables["orders"]
+			if orders == nil {
+				t.Fatalf("orders not found in %+v", s.Tables)
+			}
+			if orders.Rows != 2000000000 {
+				t.Errorf("Rows = %d", orders.Rows)
+			}
+			if orders.Indexes["idx_user_at"] != 1900000000 {
+				t.Errorf("Indexes = %v", orders.Indexes)
+			}
+			if _, ok := s.Tables["v_all"]; ok {
+				t.Errorf("View without rows should be skipped")
+			}
+		})
+	}
+
+	// Tables are qualified with TABLE_SCHEMA when it is there
+	s := ParseDump([]byte("TABLE_SCHEMA\tTABLE_NAME\tTABLE_ROWS\nshop\torders\t42\n"))
+	if ts := s.Tables["shop.orders"]; ts == nil || ts.Rows != 42 {
+		t.Errorf("Expected shop.orders, got %v", s.Tables)
+	}
+}
+
+func TestStats_Apply(t *testing.T) {
+	schema := &model.SchemaCtx{Tables: map[string]*model.Table{
+		"orders": {Name: "orders", Indexes: []*model.Index{{Name: "idx_user_id", Columns: []string{"user_id"}}}},
+	}}
+	s := &Stats{Tables: map[string]*TableStats{
+		"orders":       {Rows: 1000, Indexes: map[string]int64{"idx_user_id": 10}},
+		"shop.missing": {Rows: 5},
+	}}
+
+	missing := s.Apply(schema)
+	if len(missing) != 1 || missing[0] != "shop.missing" {
+		t.Errorf("Apply() missing = %v", missing)
+	}
+	orders := schema.Tables["orders"]
+	if orders.Rows != 1000 || orders.Indexes[0].Cardinality != 10 {
+		t.Errorf("orders = %+v, index %+v", orders, orders.Indexes[0])
+	}
+}